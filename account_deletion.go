@@ -0,0 +1,153 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/mailer"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	accountDeletionInterval = 10 * time.Minute
+	// users deleted per query, so one run never holds too many row locks
+	accountDeletionBatch = 100
+)
+
+// accountDeletionGracePeriod is how long a deleted account can still be
+// recovered by logging in. ACCOUNT_DELETION_GRACE_PERIOD overrides it.
+func accountDeletionGracePeriod() time.Duration {
+	grace := 14 * 24 * time.Hour
+	if s := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			log.Fatal("Invalid ACCOUNT_DELETION_GRACE_PERIOD:", err)
+		}
+		grace = d
+	}
+	return grace
+}
+
+// handlerDeleteUser schedules the caller's account for deletion once the
+// grace period is over and signs out all of its sessions.
+func (cfg *apiConfig) handlerDeleteUser(w http.ResponseWriter, req *http.Request) {
+	// only a real login can delete the account, not an api token
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	type parameters struct {
+		Password string `json:"password"`
+	}
+
+	type response struct {
+		DeleteAfter time.Time `json:"delete_after"`
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed deletion request", http.StatusBadRequest)
+		return
+	}
+
+	user, err := cfg.db.GetUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	// a stolen access token shouldn't be a way to guess the password
+	if !cfg.checkLoginLockout(w, req, user.Email) {
+		return
+	}
+	valid, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		cfg.recordLoginFailure(req.Context(), req, user.Email)
+		respondWithError(w, "invalid password", http.StatusUnauthorized)
+		return
+	}
+
+	deleteAfter, err := cfg.db.ScheduleUserDeletion(req.Context(), database.ScheduleUserDeletionParams{
+		ID:          user.ID,
+		DeleteAfter: sql.NullTime{Time: time.Now().Add(cfg.accountDeletionGrace), Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, "Could not schedule deletion", http.StatusInternalServerError)
+		return
+	}
+	// logging in again is how the deletion gets cancelled, so no session
+	// or api token may carry on past this point. Revoked tokens stay
+	// revoked if the deletion is cancelled.
+	if err := cfg.revokeAllSessions(req.Context(), user.ID); err != nil {
+		log.Printf("failed to revoke sessions of user %s: %s", user.ID, err)
+	}
+	if err := cfg.db.RevokeAllAPITokensForUser(req.Context(), user.ID); err != nil {
+		log.Printf("failed to revoke api tokens of user %s: %s", user.ID, err)
+	}
+	if err := cfg.sendDeletionScheduledEmail(req.Context(), user.Email, deleteAfter.Time); err != nil {
+		log.Printf("failed to send deletion email to user %s: %s", user.ID, err)
+	}
+
+	respondWithJSON(w, response{DeleteAfter: deleteAfter.Time}, http.StatusAccepted)
+}
+
+func (cfg *apiConfig) sendDeletionScheduledEmail(ctx context.Context, email string, deleteAfter time.Time) error {
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Your Chirpy account will be deleted",
+		Body: "We received a request to delete your Chirpy account.\n\n" +
+			"Your account and all of your chirps will be deleted after " +
+			deleteAfter.UTC().Format(time.RFC1123) + ".\n" +
+			"If you change your mind, log in before then and the deletion will be cancelled.\n",
+	})
+}
+
+// cancelAccountDeletion is called on every successful login.
+func (cfg *apiConfig) cancelAccountDeletion(ctx context.Context, user database.User) {
+	if !user.DeleteAfter.Valid {
+		return
+	}
+	if err := cfg.db.CancelUserDeletion(ctx, user.ID); err != nil {
+		log.Printf("failed to cancel deletion of user %s: %s", user.ID, err)
+	}
+}
+
+// startAccountDeletionWorker deletes accounts whose grace period is over.
+// Their chirps, tokens and sessions go with them through foreign key
+// cascades, and a tombstone is left in deleted_users. Instances can all run
+// the worker; each due user is locked by the one deleting it.
+func (cfg *apiConfig) startAccountDeletionWorker() {
+	go func() {
+		for range time.Tick(accountDeletionInterval) {
+			cfg.deleteDueAccounts(context.Background())
+		}
+	}()
+}
+
+func (cfg *apiConfig) deleteDueAccounts(ctx context.Context) {
+	for {
+		deleted, err := cfg.db.DeleteDueUsers(ctx, accountDeletionBatch)
+		if err != nil {
+			log.Printf("failed to delete accounts: %s", err)
+			return
+		}
+		for _, id := range deleted {
+			log.Printf("deleted account %s", id)
+		}
+		if len(deleted) < accountDeletionBatch {
+			return
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type APIToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+func apiTokenFromDB(token database.ApiToken) APIToken {
+	return APIToken{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		CreatedAt:  token.CreatedAt,
+		LastUsedAt: timeOrNil(token.LastUsedAt),
+		ExpiresAt:  timeOrNil(token.ExpiresAt),
+	}
+}
+
+func (cfg *apiConfig) handlerCreateAPIToken(w http.ResponseWriter, req *http.Request) {
+	// only a real login can mint tokens; an api token can't mint more of itself
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	type parameters struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+
+	type response struct {
+		APIToken
+		Token string `json:"token"`
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed token request", http.StatusBadRequest)
+		return
+	}
+	if params.Name == "" {
+		respondWithError(w, "token name is required", http.StatusBadRequest)
+		return
+	}
+	if len(params.Scopes) == 0 {
+		respondWithError(w, "at least one scope is required", http.StatusBadRequest)
+		return
+	}
+	for _, scope := range params.Scopes {
+		if !auth.ValidScope(scope) {
+			respondWithError(w, "unknown scope "+scope, http.StatusBadRequest)
+			return
+		}
+	}
+	if params.ExpiresInDays < 0 {
+		respondWithError(w, "expires_in_days must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	expiresAt := sql.NullTime{}
+	if params.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, params.ExpiresInDays), Valid: true}
+	}
+
+	raw, err := auth.MakeAPIToken()
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	created, err := cfg.db.CreateAPIToken(req.Context(), database.CreateAPITokenParams{
+		UserID:    userID,
+		Name:      params.Name,
+		TokenHash: auth.HashToken(raw),
+		Scopes:    params.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, "Could not create token", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, response{APIToken: apiTokenFromDB(created), Token: raw}, http.StatusCreated)
+}
+
+func (cfg *apiConfig) handlerListAPITokens(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := cfg.db.ListAPITokens(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Could not list tokens", http.StatusInternalServerError)
+		return
+	}
+
+	result := []APIToken{}
+	for _, t := range tokens {
+		result = append(result, apiTokenFromDB(t))
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
+
+func (cfg *apiConfig) handlerRevokeAPIToken(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	tokenID, err := uuid.Parse(req.PathValue("token_id"))
+	if err != nil {
+		respondWithError(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	_, err = cfg.db.RevokeAPIToken(req.Context(), database.RevokeAPITokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func timeOrNil(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
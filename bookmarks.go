@@ -0,0 +1,321 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxBookmarkFolderName = 50
+
+// Bookmark is a chirp the caller saved. Bookmarks are private, so they are
+// only ever shown to the user who made them.
+type Bookmark struct {
+	Chirp
+	BookmarkedAt time.Time `json:"bookmarked_at"`
+	// null for bookmarks that aren't in a folder
+	FolderID *uuid.UUID `json:"folder_id"`
+}
+
+type BookmarkFolder struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// requireChirpyRed responds with 403 unless user has Chirpy Red, which
+// bookmark folders need.
+func requireChirpyRed(w http.ResponseWriter, user database.User) bool {
+	if !user.IsChirpyRed.Bool {
+		respondWithError(w, "bookmark folders need Chirpy Red", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// handlerBookmarkChirp saves a chirp for the caller, optionally in one of
+// their folders. Bookmarking a chirp again moves it to the given folder, or
+// out of its folder if none is given, but keeps when it was first saved.
+func (cfg *apiConfig) handlerBookmarkChirp(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		FolderID *uuid.UUID `json:"folder_id"`
+	}
+
+	p, ok := cfg.authorize(w, req, auth.ScopeBookmarksWrite)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	// the body is optional
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, "malformed bookmark", http.StatusBadRequest)
+		return
+	}
+
+	folderID := uuid.NullUUID{}
+	if params.FolderID != nil {
+		user, err := cfg.db.GetUser(req.Context(), p.UserID)
+		if err != nil {
+			respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if !requireChirpyRed(w, user) {
+			return
+		}
+		folder, err := cfg.db.GetBookmarkFolder(req.Context(), database.GetBookmarkFolderParams{
+			ID:     *params.FolderID,
+			UserID: p.UserID,
+		})
+		if err != nil {
+			respondWithError(w, "Folder not found", http.StatusNotFound)
+			return
+		}
+		folderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	}
+
+	err = cfg.db.BookmarkChirp(req.Context(), database.BookmarkChirpParams{
+		UserID:   p.UserID,
+		ChirpID:  chirpID,
+		FolderID: folderID,
+	})
+	if isForeignKeyViolation(err) {
+		// the chirp doesn't exist, or the folder was deleted after we
+		// looked it up
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "Could not bookmark chirp", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerDeleteBookmark(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeBookmarksWrite)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	_, err = cfg.db.DeleteBookmark(req.Context(), database.DeleteBookmarkParams{
+		UserID:  p.UserID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, "Could not delete bookmark", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerListBookmarks lists the caller's bookmarks, most recently saved
+// first, a page at a time. folder_id narrows the list to one folder.
+func (cfg *apiConfig) handlerListBookmarks(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeBookmarksRead)
+	if !ok {
+		return
+	}
+	query := req.URL.Query()
+	page, err := pagination.ParseParams(query, defaultPageSize, maxPageSize)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	folderID := uuid.NullUUID{}
+	if s := query.Get("folder_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, "Invalid folder ID", http.StatusBadRequest)
+			return
+		}
+		// folders stay readable after a Chirpy Red membership ends
+		folder, err := cfg.db.GetBookmarkFolder(req.Context(), database.GetBookmarkFolderParams{
+			ID:     id,
+			UserID: p.UserID,
+		})
+		if err != nil {
+			respondWithError(w, "Folder not found", http.StatusNotFound)
+			return
+		}
+		folderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	}
+
+	beforeCreatedAt, beforeID := cursorArgs(page.After)
+	rows, err := cfg.db.ListBookmarks(req.Context(), database.ListBookmarksParams{
+		UserID:          p.UserID,
+		FolderID:        folderID,
+		BeforeCreatedAt: beforeCreatedAt,
+		BeforeID:        beforeID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, "Could not list bookmarks", http.StatusInternalServerError)
+		return
+	}
+
+	rows, more := pagination.Trim(rows, page.Limit)
+	chirps := []database.Chirp{}
+	for _, row := range rows {
+		chirps = append(chirps, database.Chirp{
+			ID:         row.ID,
+			CreatedAt:  row.CreatedAt,
+			UpdatedAt:  row.UpdatedAt,
+			Body:       row.Body,
+			UserID:     row.UserID,
+			EditedAt:   row.EditedAt,
+			InReplyTo:  row.InReplyTo,
+			ReplyCount: row.ReplyCount,
+			LikeCount:  row.LikeCount,
+			Kind:       row.Kind,
+			RechirpOf:  row.RechirpOf,
+			QuoteOf:    row.QuoteOf,
+		})
+	}
+	rendered, err := cfg.renderChirps(req, chirps)
+	if err != nil {
+		respondWithError(w, "Could not list bookmarks", http.StatusInternalServerError)
+		return
+	}
+
+	result := []Bookmark{}
+	for i, row := range rows {
+		result = append(result, Bookmark{
+			Chirp:        rendered[i],
+			BookmarkedAt: row.BookmarkedAt,
+			FolderID:     uuidOrNil(row.FolderID),
+		})
+	}
+	if more {
+		last := rows[len(rows)-1]
+		pagination.SetNextLink(w, req, pagination.Cursor{CreatedAt: last.BookmarkedAt, ID: last.ID})
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
+
+func (cfg *apiConfig) handlerCreateBookmarkFolder(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Name string `json:"name"`
+	}
+
+	p, ok := cfg.authorize(w, req, auth.ScopeBookmarksWrite)
+	if !ok {
+		return
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed folder", http.StatusBadRequest)
+		return
+	}
+	if params.Name == "" {
+		respondWithError(w, "folder name is required", http.StatusBadRequest)
+		return
+	}
+	if len(params.Name) > maxBookmarkFolderName {
+		respondWithError(w, "folder name is too long", http.StatusBadRequest)
+		return
+	}
+
+	user, err := cfg.db.GetUser(req.Context(), p.UserID)
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if !requireChirpyRed(w, user) {
+		return
+	}
+
+	folder, err := cfg.db.CreateBookmarkFolder(req.Context(), database.CreateBookmarkFolderParams{
+		UserID: user.ID,
+		Name:   params.Name,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, "you already have a folder with that name", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "Could not create folder", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, BookmarkFolder{
+		ID:        folder.ID,
+		Name:      folder.Name,
+		CreatedAt: folder.CreatedAt,
+	}, http.StatusCreated)
+}
+
+func (cfg *apiConfig) handlerListBookmarkFolders(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeBookmarksRead)
+	if !ok {
+		return
+	}
+
+	folders, err := cfg.db.ListBookmarkFolders(req.Context(), p.UserID)
+	if err != nil {
+		respondWithError(w, "Could not list folders", http.StatusInternalServerError)
+		return
+	}
+
+	result := []BookmarkFolder{}
+	for _, folder := range folders {
+		result = append(result, BookmarkFolder{
+			ID:        folder.ID,
+			Name:      folder.Name,
+			CreatedAt: folder.CreatedAt,
+		})
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
+
+// handlerDeleteBookmarkFolder deletes one of the caller's folders. The
+// bookmarks in it are kept, outside any folder.
+func (cfg *apiConfig) handlerDeleteBookmarkFolder(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeBookmarksWrite)
+	if !ok {
+		return
+	}
+	folderID, err := uuid.Parse(req.PathValue("folder_id"))
+	if err != nil {
+		respondWithError(w, "Invalid folder ID", http.StatusBadRequest)
+		return
+	}
+
+	n, err := cfg.db.DeleteBookmarkFolder(req.Context(), database.DeleteBookmarkFolderParams{
+		ID:     folderID,
+		UserID: p.UserID,
+	})
+	if err != nil {
+		respondWithError(w, "Could not delete folder", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
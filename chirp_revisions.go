@@ -0,0 +1,152 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// editWindowFromEnv reads how long chirps stay editable from key, as a Go
+// duration. "0" lets chirps be edited forever.
+func editWindowFromEnv(key string, fallback time.Duration) time.Duration {
+	s := os.Getenv(key)
+	if s == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		log.Fatalf("Invalid %s: %q", key, s)
+	}
+	return d
+}
+
+type ChirpRevision struct {
+	Body       string    `json:"body"`
+	WrittenAt  time.Time `json:"written_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// editWindow is how long after posting user may edit a chirp. Chirpy Red
+// members get longer.
+func (cfg *apiConfig) editWindow(user database.User) time.Duration {
+	if user.IsChirpyRed.Bool {
+		return cfg.redChirpEditWindow
+	}
+	return cfg.chirpEditWindow
+}
+
+// handlerEditChirp replaces a chirp's body, keeping the old body as a
+// revision anyone can see.
+func (cfg *apiConfig) handlerEditChirp(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	p, ok := cfg.authorize(w, req, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed chirp", http.StatusBadRequest)
+		return
+	}
+	if len(params.Body) > maxChirpLength {
+		respondWithJSON(w, Chirp{Valid: false}, http.StatusBadRequest)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+	if chirp.UserID != p.UserID {
+		respondWithError(w, "invalid credentials", http.StatusForbidden)
+		return
+	}
+	if chirp.Kind == chirpKindRechirp {
+		respondWithError(w, "rechirps have no body to edit", http.StatusBadRequest)
+		return
+	}
+	user, err := cfg.db.GetUser(req.Context(), p.UserID)
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if window := cfg.editWindow(user); window > 0 && time.Since(chirp.CreatedAt.Time) > window {
+		respondWithError(w, "chirp can no longer be edited", http.StatusForbidden)
+		return
+	}
+
+	body := censor(params.Body)
+	if body == chirp.Body {
+		cfg.respondWithChirp(w, req, chirp, http.StatusOK)
+		return
+	}
+
+	edited, err := cfg.db.EditChirp(req.Context(), database.EditChirpParams{
+		ID:       chirp.ID,
+		UserID:   p.UserID,
+		Hashtags: entities.Hashtags(body),
+		Mentions: entities.Mentions(body),
+		Body:     body,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// deleted since we looked it up
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "Could not edit chirp", http.StatusInternalServerError)
+		return
+	}
+
+	cfg.respondWithChirp(w, req, edited, http.StatusOK)
+}
+
+// handlerGetChirpRevisions lists the bodies a chirp had before its edits,
+// oldest first.
+func (cfg *apiConfig) handlerGetChirpRevisions(w http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := cfg.db.GetChirp(req.Context(), chirpID); err != nil {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+	revisions, err := cfg.db.ListChirpRevisions(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, "Could not list revisions", http.StatusInternalServerError)
+		return
+	}
+
+	result := []ChirpRevision{}
+	for _, revision := range revisions {
+		result = append(result, ChirpRevision{
+			Body:       revision.Body,
+			WrittenAt:  revision.WrittenAt,
+			ReplacedAt: revision.ReplacedAt,
+		})
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
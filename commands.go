@@ -0,0 +1,46 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const usage = `usage: chirpy [command]
+
+With no command, chirpy runs the server.
+
+commands:
+  bootstrap-admin <email>   make an existing user the first admin`
+
+// runCommand runs a command-line subcommand instead of the server.
+func runCommand(db *database.Queries, args []string) error {
+	switch args[0] {
+	case "bootstrap-admin":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		return bootstrapAdmin(db, args[1])
+	default:
+		return errors.New(usage)
+	}
+}
+
+// bootstrapAdmin promotes the first admin. Once there is one, further role
+// changes go through PUT /admin/users/{user_id}/role.
+func bootstrapAdmin(db *database.Queries, email string) error {
+	userID, err := db.PromoteFirstAdmin(context.Background(), email)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := db.GetUserByEmail(context.Background(), email); err != nil {
+			return fmt.Errorf("no user with email %s", email)
+		}
+		return errors.New("an admin already exists; ask them to grant the role instead")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to promote %s: %s", email, err)
+	}
+	fmt.Printf("%s (%s) is now an admin\n", email, userID)
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+func pqErrorIs(err error, name string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Name() == name
+}
+
+// isUniqueViolation reports whether err is an insert that clashed with an
+// existing row.
+func isUniqueViolation(err error) bool {
+	return pqErrorIs(err, "unique_violation")
+}
+
+// violatesConstraint reports whether err broke the named constraint or
+// unique index, for tables with more than one.
+func violatesConstraint(err error, name string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Constraint == name
+}
+
+// isForeignKeyViolation reports whether err is a write that referenced a
+// row that doesn't exist (anymore).
+func isForeignKeyViolation(err error) bool {
+	return pqErrorIs(err, "foreign_key_violation")
+}
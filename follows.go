@@ -0,0 +1,196 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// cursorArgs turns a page's cursor into the created_at and id query
+// arguments that continue past it, in whichever direction the query sorts.
+// They are null for the first page.
+func cursorArgs(cursor *pagination.Cursor) (sql.NullTime, uuid.NullUUID) {
+	if cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: cursor.CreatedAt, Valid: true}, uuid.NullUUID{UUID: cursor.ID, Valid: true}
+}
+
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeFollowsWrite)
+	if !ok {
+		return
+	}
+	followeeID, err := uuid.Parse(req.PathValue("user_id"))
+	if err != nil {
+		respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if followeeID == p.UserID {
+		respondWithError(w, "you can't follow yourself", http.StatusBadRequest)
+		return
+	}
+
+	// following someone twice is not an error
+	_, err = cfg.db.FollowUser(req.Context(), database.FollowUserParams{
+		FollowerID: p.UserID,
+		FolloweeID: followeeID,
+	})
+	if isForeignKeyViolation(err) {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "Could not follow user", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeFollowsWrite)
+	if !ok {
+		return
+	}
+	followeeID, err := uuid.Parse(req.PathValue("user_id"))
+	if err != nil {
+		respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	_, err = cfg.db.UnfollowUser(req.Context(), database.UnfollowUserParams{
+		FollowerID: p.UserID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, "Could not unfollow user", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerListFollowers and handlerListFollowing list a user's follows,
+// newest first, a page at a time.
+func (cfg *apiConfig) handlerListFollowers(w http.ResponseWriter, req *http.Request) {
+	cfg.listFollows(w, req, func(userID uuid.UUID, page pagination.Params) ([]Follow, error) {
+		beforeCreatedAt, beforeID := cursorArgs(page.After)
+		rows, err := cfg.db.ListFollowers(req.Context(), database.ListFollowersParams{
+			UserID:          userID,
+			BeforeCreatedAt: beforeCreatedAt,
+			BeforeID:        beforeID,
+			PageSize:        int32(page.Limit + 1),
+		})
+		follows := []Follow{}
+		for _, row := range rows {
+			follows = append(follows, Follow{UserID: row.UserID, FollowedAt: row.CreatedAt})
+		}
+		return follows, err
+	})
+}
+
+func (cfg *apiConfig) handlerListFollowing(w http.ResponseWriter, req *http.Request) {
+	cfg.listFollows(w, req, func(userID uuid.UUID, page pagination.Params) ([]Follow, error) {
+		beforeCreatedAt, beforeID := cursorArgs(page.After)
+		rows, err := cfg.db.ListFollowing(req.Context(), database.ListFollowingParams{
+			UserID:          userID,
+			BeforeCreatedAt: beforeCreatedAt,
+			BeforeID:        beforeID,
+			PageSize:        int32(page.Limit + 1),
+		})
+		follows := []Follow{}
+		for _, row := range rows {
+			follows = append(follows, Follow{UserID: row.UserID, FollowedAt: row.CreatedAt})
+		}
+		return follows, err
+	})
+}
+
+// listFollows responds with the page of follows that list fetches, which
+// asks for one more than the page size to find out if there is another.
+func (cfg *apiConfig) listFollows(w http.ResponseWriter, req *http.Request, list func(uuid.UUID, pagination.Params) ([]Follow, error)) {
+	userID, err := uuid.Parse(req.PathValue("user_id"))
+	if err != nil {
+		respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	page, err := pagination.ParseParams(req.URL.Query(), defaultPageSize, maxPageSize)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := cfg.db.GetUser(req.Context(), userID); err != nil {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	follows, err := list(userID, page)
+	if err != nil {
+		respondWithError(w, "Could not list follows", http.StatusInternalServerError)
+		return
+	}
+	follows, more := pagination.Trim(follows, page.Limit)
+	if more {
+		last := follows[len(follows)-1]
+		pagination.SetNextLink(w, req, pagination.Cursor{CreatedAt: last.FollowedAt, ID: last.UserID})
+	}
+	respondWithJSON(w, follows, http.StatusOK)
+}
+
+// handlerGetTimeline lists chirps by the people the caller follows and by
+// the caller, newest first, a page at a time.
+func (cfg *apiConfig) handlerGetTimeline(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeChirpsRead)
+	if !ok {
+		return
+	}
+	page, err := pagination.ParseParams(req.URL.Query(), defaultPageSize, maxPageSize)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	beforeCreatedAt, beforeID := cursorArgs(page.After)
+	rows, err := cfg.db.GetTimeline(req.Context(), database.GetTimelineParams{
+		UserID:          p.UserID,
+		BeforeCreatedAt: beforeCreatedAt,
+		BeforeID:        beforeID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, "Could not get timeline", http.StatusInternalServerError)
+		return
+	}
+
+	rows, more := pagination.Trim(rows, page.Limit)
+	chirps := []database.Chirp{}
+	for _, row := range rows {
+		chirps = append(chirps, database.Chirp(row))
+	}
+	result, err := cfg.renderChirps(req, chirps)
+	if err != nil {
+		respondWithError(w, "Could not get timeline", http.StatusInternalServerError)
+		return
+	}
+	if more {
+		last := rows[len(rows)-1]
+		pagination.SetNextLink(w, req, pagination.Cursor{CreatedAt: last.CreatedAt.Time, ID: last.ID})
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"chirpy/internal/pagination"
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	trendingInterval = 5 * time.Minute
+	// only uses in the last trendingWindow count, and each counts half as
+	// much every trendingHalfLife, so new bursts beat old favourites
+	trendingWindow      = 24 * time.Hour
+	trendingHalfLife    = 2 * time.Hour
+	maxTrendingTags     = 50
+	defaultTrendingTags = 10
+)
+
+type TrendingHashtag struct {
+	Tag        string  `json:"tag"`
+	Score      float64 `json:"score"`
+	ChirpCount int32   `json:"chirp_count"`
+}
+
+// handlerGetHashtagChirps lists the chirps using a hashtag, newest first,
+// a page at a time. The tag is matched the way hashtags are stored, so
+// Go, go and #Go are all the same tag.
+func (cfg *apiConfig) handlerGetHashtagChirps(w http.ResponseWriter, req *http.Request) {
+	tag, ok := entities.NormalizeHashtag(req.PathValue("tag"))
+	if !ok {
+		respondWithError(w, "Invalid hashtag", http.StatusBadRequest)
+		return
+	}
+	page, err := pagination.ParseParams(req.URL.Query(), defaultPageSize, maxPageSize)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	beforeCreatedAt, beforeID := cursorArgs(page.After)
+	chirps, err := cfg.db.ListHashtagChirps(req.Context(), database.ListHashtagChirpsParams{
+		Tag:             tag,
+		BeforeCreatedAt: beforeCreatedAt,
+		BeforeID:        beforeID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, "Could not list chirps", http.StatusInternalServerError)
+		return
+	}
+
+	chirps, more := pagination.Trim(chirps, page.Limit)
+	result, err := cfg.renderChirps(req, chirps)
+	if err != nil {
+		respondWithError(w, "Could not list chirps", http.StatusInternalServerError)
+		return
+	}
+	if more {
+		last := chirps[len(chirps)-1]
+		pagination.SetNextLink(w, req, pagination.Cursor{CreatedAt: last.CreatedAt.Time, ID: last.ID})
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
+
+// handlerGetTrending lists the tags rising fastest, highest score first,
+// as of the trending worker's last run.
+func (cfg *apiConfig) handlerGetTrending(w http.ResponseWriter, req *http.Request) {
+	limit := defaultTrendingTags
+	if s := req.URL.Query().Get("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxTrendingTags {
+			respondWithError(w, "limit must be between 1 and "+strconv.Itoa(maxTrendingTags), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tags, err := cfg.db.ListTrendingHashtags(req.Context(), int32(limit))
+	if err != nil {
+		respondWithError(w, "Could not get trending hashtags", http.StatusInternalServerError)
+		return
+	}
+
+	result := []TrendingHashtag{}
+	for _, tag := range tags {
+		result = append(result, TrendingHashtag{
+			Tag:        tag.Tag,
+			Score:      tag.Score,
+			ChirpCount: tag.ChirpCount,
+		})
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
+
+// startTrendingWorker recomputes the trending hashtags now and then every
+// trendingInterval. Every instance can run it; each run replaces the whole
+// list in one statement.
+func (cfg *apiConfig) startTrendingWorker() {
+	go func() {
+		cfg.refreshTrending(context.Background())
+		for range time.Tick(trendingInterval) {
+			cfg.refreshTrending(context.Background())
+		}
+	}()
+}
+
+func (cfg *apiConfig) refreshTrending(ctx context.Context) {
+	err := cfg.db.RefreshTrendingHashtags(ctx, database.RefreshTrendingHashtagsParams{
+		WindowSeconds:   trendingWindow.Seconds(),
+		HalfLifeSeconds: trendingHalfLife.Seconds(),
+		MaxTags:         maxTrendingTags,
+	})
+	if err != nil {
+		log.Printf("failed to refresh trending hashtags: %s", err)
+	}
+}
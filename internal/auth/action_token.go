@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	ActionVerifyEmail = "verify-email"
+	// exchanged for a session once the second factor is checked
+	ActionMFA = "mfa-pending"
+)
+
+// MakeActionToken signs a token that authorizes exactly one kind of action
+// (its audience) instead of general API access. tokenID is carried as the
+// JWT ID so callers can record it and refuse to accept it twice.
+func MakeActionToken(signer Signer, userID, tokenID uuid.UUID, action string, expiresIn time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+		Subject:   userID.String(),
+		Audience:  jwt.ClaimStrings{action},
+		ID:        tokenID.String(),
+	}
+	return signer.Sign(claims)
+}
+
+func ValidateActionToken(signer Signer, tokenString string, action string) (userID uuid.UUID, tokenID uuid.UUID, err error) {
+	claims := jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, &claims, signer.Keyfunc,
+		jwt.WithValidMethods(signer.Algorithms()), jwt.WithAudience(action), jwt.WithIssuer("chirpy"))
+	if err != nil {
+		return uuid.UUID{}, uuid.UUID{}, fmt.Errorf("action token is invalid or expired: %s", err)
+	}
+	userID, err = uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.UUID{}, uuid.UUID{}, fmt.Errorf("failed to parse uuid from subject: %s", err)
+	}
+	tokenID, err = uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.UUID{}, uuid.UUID{}, fmt.Errorf("failed to parse uuid from token id: %s", err)
+	}
+	return userID, tokenID, nil
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestActionTokenPipeline(t *testing.T) {
+	userID, tokenID := uuid.New(), uuid.New()
+	ss, err := MakeActionToken(NewHMACSigner("secret"), userID, tokenID, ActionVerifyEmail, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotUserID, gotTokenID, err := ValidateActionToken(NewHMACSigner("secret"), ss, ActionVerifyEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotUserID != userID || gotTokenID != tokenID {
+		t.Fatal("parsed ids do not match the original ids")
+	}
+}
+
+func TestActionTokenRejectsOtherAction(t *testing.T) {
+	ss, err := MakeActionToken(NewHMACSigner("secret"), uuid.New(), uuid.New(), ActionVerifyEmail, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = ValidateActionToken(NewHMACSigner("secret"), ss, "something-else")
+	if err == nil {
+		t.Fatalf("Expected an error, got nil")
+	}
+}
+
+func TestActionTokenIsNotAnAccessToken(t *testing.T) {
+	ss, err := MakeActionToken(NewHMACSigner("secret"), uuid.New(), uuid.New(), ActionVerifyEmail, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ValidateJWT(ss, "secret")
+	if err == nil {
+		t.Fatalf("Expected an error, got nil")
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import "testing"
+
+func TestMakeAPIToken(t *testing.T) {
+	token, err := MakeAPIToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsAPIToken(token) {
+		t.Fatalf("token %s does not carry the api token prefix", token)
+	}
+	other, err := MakeAPIToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == other {
+		t.Fatal("expected two different tokens")
+	}
+}
+
+func TestOAuthAccessTokenIsAnAPIToken(t *testing.T) {
+	token, err := MakeOAuthAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsAPIToken(token) {
+		t.Fatalf("token %s is not recognized as an api token", token)
+	}
+}
+
+func TestJWTIsNotAnAPIToken(t *testing.T) {
+	if IsAPIToken("eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.e30.sig") {
+		t.Fatal("expected false, got true")
+	}
+}
+
+func TestValidScope(t *testing.T) {
+	if !ValidScope(ScopeChirpsWrite) {
+		t.Fatal("expected true, got false")
+	}
+	if ValidScope("admin:everything") {
+		t.Fatal("expected false, got true")
+	}
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// hashes are looked up by their first five hex characters, the same
+// k-anonymity ranges the Pwned Passwords API serves
+const breachRangeLength = 5
+
+// BreachedPasswords checks passwords against a local copy of a breach
+// corpus in the Pwned Passwords format: uppercase SHA-1 hashes, one per
+// line, optionally followed by ":count". It is either a single file, which
+// is loaded into memory, or a directory of range files named by their
+// five-character prefix and holding the rest of each hash, as the Pwned
+// Passwords downloader writes them. A directory is read one range at a time,
+// so the whole corpus never has to fit in memory.
+type BreachedPasswords struct {
+	dir    string
+	ranges map[string][]string
+}
+
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %s", err)
+	}
+	if info.IsDir() {
+		return &BreachedPasswords{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %s", err)
+	}
+	defer f.Close()
+
+	b := &BreachedPasswords{ranges: map[string][]string{}}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash := parseBreachLine(scanner.Text())
+		if hash == "" {
+			continue
+		}
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("breached password list has a malformed hash %q", hash)
+		}
+		prefix := hash[:breachRangeLength]
+		b.ranges[prefix] = append(b.ranges[prefix], hash[breachRangeLength:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %s", err)
+	}
+	for _, suffixes := range b.ranges {
+		sort.Strings(suffixes)
+	}
+	return b, nil
+}
+
+// Contains reports whether password appears in the corpus.
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	suffixes, err := b.suffixes(hash[:breachRangeLength])
+	if err != nil {
+		return false, err
+	}
+	suffix := hash[breachRangeLength:]
+	i := sort.SearchStrings(suffixes, suffix)
+	return i < len(suffixes) && suffixes[i] == suffix, nil
+}
+
+// suffixes returns the sorted hash suffixes in the range for prefix.
+func (b *BreachedPasswords) suffixes(prefix string) ([]string, error) {
+	if b.dir == "" {
+		return b.ranges[prefix], nil
+	}
+
+	f, err := os.Open(filepath.Join(b.dir, prefix))
+	if errors.Is(err, fs.ErrNotExist) {
+		f, err = os.Open(filepath.Join(b.dir, prefix+".txt"))
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password range %s: %s", prefix, err)
+	}
+	defer f.Close()
+
+	suffixes := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if suffix := parseBreachLine(scanner.Text()); suffix != "" {
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password range %s: %s", prefix, err)
+	}
+	sort.Strings(suffixes)
+	return suffixes, nil
+}
+
+func parseBreachLine(line string) string {
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	return strings.ToUpper(hash)
+}
@@ -9,18 +9,31 @@ import (
 	"time"
 )
 
-func MakeJWT(userID uuid.UUID, secret string, expiresIn time.Duration) (string, error) {
+// AccessClaims are the claims of an access token. Role is the user's role
+// when the token was issued.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role,omitempty"`
+}
+
+func MakeJWT(userID uuid.UUID, role string, secret string, expiresIn time.Duration) (string, error) {
+	return MakeJWTWithSigner(NewHMACSigner(secret), userID, role, expiresIn)
+}
+
+func MakeJWTWithSigner(signer Signer, userID uuid.UUID, role string, expiresIn time.Duration) (string, error) {
 	now := time.Now()
 	issuedAt := jwt.NewNumericDate(now)
 	expiresAt := jwt.NewNumericDate(now.Add(expiresIn))
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  issuedAt,
-		ExpiresAt: expiresAt,
-		Subject:   userID.String(),
+	claims := AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  issuedAt,
+			ExpiresAt: expiresAt,
+			Subject:   userID.String(),
+		},
+		Role: role,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedString, err := token.SignedString([]byte(secret))
+	signedString, err := signer.Sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -28,22 +41,36 @@ func MakeJWT(userID uuid.UUID, secret string, expiresIn time.Duration) (string,
 }
 
 func ValidateJWT(tokenString string, secret string) (uuid.UUID, error) {
-	parser := func(*jwt.Token) (any, error) {
-		return []byte(secret), nil
-	}
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, parser)
+	return ValidateJWTWithSigner(tokenString, NewHMACSigner(secret))
+}
+
+func ValidateJWTWithSigner(tokenString string, signer Signer) (uuid.UUID, error) {
+	userID, _, err := ValidateAccessToken(tokenString, signer)
+	return userID, err
+}
+
+// ValidateAccessToken is ValidateJWTWithSigner that also returns the role
+// claim. Tokens issued before roles existed have an empty role.
+func ValidateAccessToken(tokenString string, signer Signer) (uuid.UUID, string, error) {
+	claims := &AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, signer.Keyfunc, jwt.WithValidMethods(signer.Algorithms()))
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("token string is invalid or expired: %s", err)
+		return uuid.UUID{}, "", fmt.Errorf("token string is invalid or expired: %s", err)
+	}
+	// access tokens never carry an audience; action tokens always do
+	audience, err := token.Claims.GetAudience()
+	if err != nil || len(audience) > 0 {
+		return uuid.UUID{}, "", fmt.Errorf("token is not an access token")
 	}
 	subject, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("token was parsed, but failed to get subject: %s", err)
+		return uuid.UUID{}, "", fmt.Errorf("token was parsed, but failed to get subject: %s", err)
 	}
 	userID, err := uuid.Parse(subject)
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("failed to parse uuid from string to uuid.UUID: %s", err)
+		return uuid.UUID{}, "", fmt.Errorf("failed to parse uuid from string to uuid.UUID: %s", err)
 	}
-	return userID, nil
+	return userID, claims.Role, nil
 }
 
 func GetBearerToken(header http.Header) string {
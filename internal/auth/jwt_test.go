@@ -10,7 +10,7 @@ import (
 
 func TestJWTPipeline(t *testing.T) {
 	userID := uuid.New()
-	ss, err := MakeJWT(userID, "secret", time.Minute)
+	ss, err := MakeJWT(userID, RoleUser, "secret", time.Minute)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -23,9 +23,28 @@ func TestJWTPipeline(t *testing.T) {
 	}
 }
 
+func TestAccessTokenCarriesRole(t *testing.T) {
+	signer := NewHMACSigner("secret")
+	userID := uuid.New()
+	ss, err := MakeJWTWithSigner(signer, userID, RoleModerator, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedUserID, role, err := ValidateAccessToken(ss, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsedUserID != userID {
+		t.Fatal("parsed user id does not match original user id")
+	}
+	if role != RoleModerator {
+		t.Fatalf("got role %q, want %q", role, RoleModerator)
+	}
+}
+
 func TestInvalidSecretFailsToValidate(t *testing.T) {
 	userID := uuid.New()
-	ss, err := MakeJWT(userID, "secret", 1*time.Minute)
+	ss, err := MakeJWT(userID, RoleUser, "secret", 1*time.Minute)
 	_, err = ValidateJWT(ss, "notthesecret")
 	if err == nil {
 		t.Fatalf("Expected an error, got nil")
@@ -34,7 +53,7 @@ func TestInvalidSecretFailsToValidate(t *testing.T) {
 
 func TestTokenDoesNotParseIfExpired(t *testing.T) {
 	userID := uuid.New()
-	ss, err := MakeJWT(userID, "secret", 1*time.Second)
+	ss, err := MakeJWT(userID, RoleUser, "secret", 1*time.Second)
 	time.Sleep(2 * time.Second)
 	_, err = ValidateJWT(ss, "secret")
 	if err == nil {
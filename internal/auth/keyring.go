@@ -0,0 +1,294 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+	private   crypto.Signer
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+// Keyring signs with asymmetric keys kept as PEM files in a directory, one key
+// per file, named <kid>.pem. The key whose name sorts last signs new tokens;
+// older keys keep verifying tokens until the key after them has been in use
+// for longer than retention. Callers must not sign tokens that last longer
+// than retention, so that everything a key signed has expired by then.
+//
+// A key's age is its file's modification time. Copying a key without
+// preserving it, or touching the file, restarts the clock, which keeps the
+// key before it verifying for longer than retention.
+type Keyring struct {
+	dir       string
+	retention time.Duration
+
+	mu       sync.RWMutex
+	keys     []*SigningKey
+	active   *SigningKey
+	loadedAt time.Time
+}
+
+// an unknown kid triggers at most one reload in this window, so garbage
+// tokens can't make every request hit the disk
+const keyringReloadInterval = 10 * time.Second
+
+func LoadKeyring(dir string, retention time.Duration) (*Keyring, error) {
+	k := &Keyring{dir: dir, retention: retention}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload rereads the key directory, picking up keys rotated in by other
+// instances sharing it.
+func (k *Keyring) Reload() error {
+	paths, err := filepath.Glob(filepath.Join(k.dir, "*.pem"))
+	if err != nil {
+		return fmt.Errorf("failed to list keys: %s", err)
+	}
+	sort.Strings(paths)
+
+	keys := []*SigningKey{}
+	for _, path := range paths {
+		key, err := readSigningKey(path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	now := time.Now()
+	current := []*SigningKey{}
+	for i, key := range keys {
+		if i+1 < len(keys) && now.Sub(keys[i+1].CreatedAt) > k.retention {
+			continue
+		}
+		current = append(current, key)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = current
+	k.loadedAt = now
+	k.active = nil
+	if len(current) > 0 {
+		k.active = current[len(current)-1]
+	}
+	return nil
+}
+
+// Rotate generates a new key of the given algorithm and makes it the one
+// that signs new tokens. Existing keys stay valid for verification.
+func (k *Keyring) Rotate(algorithm string) (*SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %s", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %s", err)
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	id := time.Now().UTC().Format("20060102T150405.000000000Z") + "-" + hex.EncodeToString(suffix)
+
+	if err := os.MkdirAll(k.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %s", err)
+	}
+	path := filepath.Join(k.dir, id+".pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, block, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write key: %s", err)
+	}
+
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k.Active()
+}
+
+func (k *Keyring) Active() (*SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.active == nil {
+		return nil, errors.New("keyring has no signing keys")
+	}
+	return k.active, nil
+}
+
+func (k *Keyring) lookup(id string) *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key
+		}
+	}
+	return nil
+}
+
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	key, err := k.Active()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+func (k *Keyring) Keyfunc(token *jwt.Token) (any, error) {
+	id, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("token has no key id")
+	}
+	key := k.lookup(id)
+	if key == nil && k.stale() {
+		// another instance may have rotated since we last looked
+		if err := k.Reload(); err != nil {
+			return nil, err
+		}
+		key = k.lookup(id)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("unknown or retired key %q", id)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("key %q does not sign with %s", id, token.Method.Alg())
+	}
+	return key.private.Public(), nil
+}
+
+func (k *Keyring) stale() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return time.Since(k.loadedAt) > keyringReloadInterval
+}
+
+func (k *Keyring) Algorithms() []string {
+	return []string{AlgorithmEdDSA, AlgorithmRS256}
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publishes the public half of every key that may still verify a token.
+func (k *Keyring) JWKS() JWKSet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.keys {
+		jwk := JWK{ID: key.ID, Algorithm: key.Algorithm, Use: "sig"}
+		switch public := key.private.Public().(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func readSigningKey(path string) (*SigningKey, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %s", path, err)
+	}
+	block, _ := pem.Decode(dat)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s holds an unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %s", path, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat key %s: %s", path, err)
+	}
+	key := &SigningKey{
+		ID:        strings.TrimSuffix(filepath.Base(path), ".pem"),
+		CreatedAt: info.ModTime(),
+	}
+	switch private := parsed.(type) {
+	case ed25519.PrivateKey:
+		key.Algorithm = AlgorithmEdDSA
+		key.private = private
+	case *rsa.PrivateKey:
+		key.Algorithm = AlgorithmRS256
+		key.private = private
+	default:
+		return nil, fmt.Errorf("%s is neither an Ed25519 nor an RSA key", path)
+	}
+	return key, nil
+}
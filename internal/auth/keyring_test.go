@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestKeyringPipeline(t *testing.T) {
+	keyring, err := LoadKeyring(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keyring.Rotate(AlgorithmEdDSA); err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.New()
+	ss, err := MakeJWTWithSigner(keyring, userID, RoleUser, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedUserID, err := ValidateJWTWithSigner(ss, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsedUserID != userID {
+		t.Fatal("parsed user id does not match original user id")
+	}
+}
+
+func TestKeyringKeepsOldKeysAfterRotation(t *testing.T) {
+	keyring, err := LoadKeyring(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := keyring.Rotate(AlgorithmEdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := MakeJWTWithSigner(keyring, uuid.New(), RoleUser, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := keyring.Rotate(AlgorithmRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == second.ID {
+		t.Fatal("rotation did not produce a new key")
+	}
+	if _, err := ValidateJWTWithSigner(ss, keyring); err != nil {
+		t.Fatalf("token signed before rotation no longer validates: %s", err)
+	}
+	if got := len(keyring.JWKS().Keys); got != 2 {
+		t.Fatalf("got %d keys in the JWKS, want 2", got)
+	}
+}
+
+func TestKeyringRetiresKeysAfterRetention(t *testing.T) {
+	dir := t.TempDir()
+	keyring, err := LoadKeyring(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keyring.Rotate(AlgorithmEdDSA); err != nil {
+		t.Fatal(err)
+	}
+	ss, err := MakeJWTWithSigner(keyring, uuid.New(), RoleUser, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := keyring.Rotate(AlgorithmEdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pretend the newer key has been signing for longer than the retention
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, second.ID+".pem"), past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ValidateJWTWithSigner(ss, keyring); err == nil {
+		t.Fatalf("Expected an error, got nil")
+	}
+	if got := len(keyring.JWKS().Keys); got != 1 {
+		t.Fatalf("got %d keys in the JWKS, want 1", got)
+	}
+}
+
+func TestKeyringLoadsPKCS1RSAKey(t *testing.T) {
+	dir := t.TempDir()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)})
+	if err := os.WriteFile(filepath.Join(dir, "legacy.pem"), block, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := LoadKeyring(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, err := keyring.Active()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active.ID != "legacy" || active.Algorithm != AlgorithmRS256 {
+		t.Fatalf("got key %s (%s), want legacy (RS256)", active.ID, active.Algorithm)
+	}
+	jwk := keyring.JWKS().Keys[0]
+	if jwk.KeyType != "RSA" || jwk.E != "AQAB" {
+		t.Fatalf("unexpected jwk %+v", jwk)
+	}
+}
+
+func TestKeyringRejectsHMACTokens(t *testing.T) {
+	keyring, err := LoadKeyring(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, err := keyring.Rotate(AlgorithmEdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.RegisteredClaims{Subject: uuid.NewString(), ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = active.ID
+	ss, err := token.SignedString([]byte("guessed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateJWTWithSigner(ss, keyring); err == nil {
+		t.Fatalf("Expected an error, got nil")
+	}
+}
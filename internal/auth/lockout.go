@@ -0,0 +1,55 @@
+package auth
+
+import "time"
+
+// LockoutPolicy decides how long to refuse logins after repeated failures.
+// The first FreeAttempts failures cost nothing, after that each failure
+// doubles the wait (from BaseDelay, up to MaxDelay), and at
+// LockoutThreshold failures the key is locked for LockoutDuration.
+type LockoutPolicy struct {
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// failures older than Window are forgotten
+	Window time.Duration
+}
+
+var DefaultAccountLockoutPolicy = LockoutPolicy{
+	FreeAttempts:     3,
+	BaseDelay:        1 * time.Second,
+	MaxDelay:         5 * time.Minute,
+	LockoutThreshold: 10,
+	LockoutDuration:  15 * time.Minute,
+	Window:           24 * time.Hour,
+}
+
+// one address may be shared by many people, so it gets far more slack
+var DefaultIPLockoutPolicy = LockoutPolicy{
+	FreeAttempts:     20,
+	BaseDelay:        1 * time.Second,
+	MaxDelay:         5 * time.Minute,
+	LockoutThreshold: 100,
+	LockoutDuration:  1 * time.Hour,
+	Window:           1 * time.Hour,
+}
+
+// Delay returns how long to refuse further attempts after the given number
+// of consecutive failures.
+func (p LockoutPolicy) Delay(failures int) time.Duration {
+	if p.LockoutThreshold > 0 && failures >= p.LockoutThreshold {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return min(delay, p.MaxDelay)
+}
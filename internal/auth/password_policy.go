@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleStrength  = "strength"
+	RuleBreached  = "breached"
+)
+
+// PasswordPolicy decides which passwords users may choose. Lengths count
+// characters, not bytes. MaxLength bounds how much work hashing a password
+// can cause.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// lowest acceptable PasswordScore, from 0 to 4
+	MinScore int
+	// optional; when set, passwords found in it are refused
+	Breached *BreachedPasswords
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength: 8,
+	MaxLength: 128,
+	MinScore:  2,
+}
+
+// PasswordViolation is one rule a password breaks.
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Check returns every rule password breaks, or nil if it is acceptable.
+// userInputs are strings the password shouldn't be based on, like the
+// user's email address. An error means the breach list couldn't be read.
+func (p PasswordPolicy) Check(password string, userInputs ...string) ([]PasswordViolation, error) {
+	var violations []PasswordViolation
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("must be at least %d characters long", p.MinLength),
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		// don't spend time scoring or hashing an oversized password
+		return append(violations, PasswordViolation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("must be at most %d characters long", p.MaxLength),
+		}), nil
+	}
+	if PasswordScore(password, userInputs...) < p.MinScore {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleStrength,
+			Message: "is too easy to guess; avoid common words, names, sequences and repeated characters",
+		})
+	}
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			violations = append(violations, PasswordViolation{
+				Rule:    RuleBreached,
+				Message: "has appeared in a data breach and must not be used",
+			})
+		}
+	}
+	return violations, nil
+}
+
+// a few of the most used passwords and words they're built from; a breach
+// list catches far more, but this works without one
+var commonPasswords = []string{
+	"password", "passw0rd", "p@ssw0rd", "qwerty", "letmein", "welcome",
+	"monkey", "dragon", "football", "baseball", "soccer", "iloveyou",
+	"admin", "login", "master", "shadow", "sunshine", "princess",
+	"superman", "batman", "trustno1", "hello", "freedom", "whatever",
+	"starwars", "michael", "jennifer", "jordan", "hunter", "charlie",
+	"killer", "computer", "summer", "winter", "secret", "access",
+	"flower", "cheese", "pepper", "ninja", "mustang", "matrix", "chirpy",
+	"abc123", "1q2w3e4r", "zaq12wsx", "696969", "123123", "654321",
+}
+
+// keyboard rows, for spotting walks like "asdf"
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// PasswordScore estimates how hard password is to guess, on zxcvbn's scale:
+// 0 is trivially guessable and 4 is very unguessable. Like zxcvbn it
+// discounts common passwords, the user's own details, repeated characters,
+// sequences like "abc" or "123", and keyboard walks like "qwerty", then
+// estimates the number of guesses a brute-force search would need for the
+// rest.
+func PasswordScore(password string, userInputs ...string) int {
+	lower := strings.ToLower(password)
+	for _, common := range commonPasswords {
+		if lower == common {
+			return 0
+		}
+	}
+
+	// a guessable chunk is worth about one random character
+	for _, input := range userInputs {
+		for _, part := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len(part) >= 3 {
+				lower = strings.ReplaceAll(lower, part, "\x00")
+			}
+		}
+	}
+	for _, common := range commonPasswords {
+		lower = strings.ReplaceAll(lower, common, "\x00")
+	}
+
+	// repeats, sequences and keyboard walks only count for a tenth of a
+	// character each
+	runes := []rune(lower)
+	effective := 0.0
+	for i, r := range runes {
+		if i > 0 && predictable(runes[i-1], r) {
+			effective += 0.1
+			continue
+		}
+		effective += 1
+	}
+
+	guesses := effective * math.Log10(float64(charsetSize(password)))
+	switch {
+	case guesses < 3:
+		return 0
+	case guesses < 6:
+		return 1
+	case guesses < 8:
+		return 2
+	case guesses < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func predictable(prev, r rune) bool {
+	if r == prev || r == prev+1 || r == prev-1 {
+		return r != 0
+	}
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, prev)
+		j := strings.IndexRune(row, r)
+		if i >= 0 && j >= 0 && (i-j == 1 || j-i == 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// charsetSize is the size of the alphabet a brute-force search would need
+// to cover every character class the password uses.
+func charsetSize(password string) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	size := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			size += class.size
+		}
+	}
+	return max(size, 2)
+}
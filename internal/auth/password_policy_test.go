@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func violatedRules(t *testing.T, policy PasswordPolicy, password string, userInputs ...string) []string {
+	t.Helper()
+	violations, err := policy.Check(password, userInputs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []string{}
+	for _, v := range violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestPasswordPolicyAcceptsStrongPassword(t *testing.T) {
+	rules := violatedRules(t, DefaultPasswordPolicy, "correct horse battery staple")
+	if len(rules) != 0 {
+		t.Fatalf("expected no violations, got %v", rules)
+	}
+}
+
+func TestPasswordPolicyLengths(t *testing.T) {
+	rules := violatedRules(t, DefaultPasswordPolicy, "x7#Qp")
+	if len(rules) == 0 || rules[0] != RuleMinLength {
+		t.Fatalf("expected %s, got %v", RuleMinLength, rules)
+	}
+	rules = violatedRules(t, DefaultPasswordPolicy, strings.Repeat("x7#Qp", 30))
+	if len(rules) != 1 || rules[0] != RuleMaxLength {
+		t.Fatalf("expected %s, got %v", RuleMaxLength, rules)
+	}
+}
+
+func TestPasswordScoreRejectsGuessablePasswords(t *testing.T) {
+	for _, password := range []string{"password", "Password", "aaaaaaaaaaaa", "abcdefgh123", "qwertyuiop", "password123"} {
+		if score := PasswordScore(password); score >= DefaultPasswordPolicy.MinScore {
+			t.Errorf("PasswordScore(%q) = %d, want below %d", password, score, DefaultPasswordPolicy.MinScore)
+		}
+	}
+}
+
+func TestPasswordScoreDiscountsUserInputs(t *testing.T) {
+	password := "waltersobchak1"
+	without := PasswordScore(password)
+	with := PasswordScore(password, "walter.sobchak@example.com")
+	if with >= without {
+		t.Fatalf("expected the email to lower the score, got %d with and %d without", with, without)
+	}
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestBreachedPasswordsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	list := sha1Hex("Tr0ub4dor&3") + ":42\n" + sha1Hex("something else") + ":1\n"
+	if err := os.WriteFile(path, []byte(list), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	breached, err := LoadBreachedPasswords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := DefaultPasswordPolicy
+	policy.Breached = breached
+	rules := violatedRules(t, policy, "Tr0ub4dor&3")
+	if len(rules) != 1 || rules[0] != RuleBreached {
+		t.Fatalf("expected %s, got %v", RuleBreached, rules)
+	}
+	if rules := violatedRules(t, policy, "correct horse battery staple"); len(rules) != 0 {
+		t.Fatalf("expected no violations, got %v", rules)
+	}
+}
+
+func TestBreachedPasswordsRangeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha1Hex("Tr0ub4dor&3")
+	if err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(hash[5:]+":42\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	breached, err := LoadBreachedPasswords(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := breached.Contains("Tr0ub4dor&3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Fatal("expected true, got false")
+	}
+	got, err = breached.Contains("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got {
+		t.Fatal("expected false, got true")
+	}
+}
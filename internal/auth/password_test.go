@@ -45,3 +45,49 @@ func TestCheckPasswordHashWithInvalidPassword(t *testing.T) {
 		t.Fatal("expected false, got true")
 	}
 }
+
+func TestNeedsRehashWithCurrentParams(t *testing.T) {
+	hashed, err := HashPassword(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NeedsRehash(hashed, DefaultPasswordParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got {
+		t.Fatal("expected false, got true")
+	}
+}
+
+func TestNeedsRehashWithStrongerParams(t *testing.T) {
+	weak := DefaultPasswordParams
+	weak.Memory = 8 * 1024
+	hashed, err := HashPasswordWithParams(pass, weak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NeedsRehash(hashed, DefaultPasswordParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Fatal("expected true, got false")
+	}
+}
+
+func TestNeedsRehashIgnoresParallelism(t *testing.T) {
+	params := DefaultPasswordParams
+	params.Parallelism = params.Parallelism + 1
+	hashed, err := HashPassword(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NeedsRehash(hashed, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got {
+		t.Fatal("expected false, got true")
+	}
+}
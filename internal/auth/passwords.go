@@ -5,8 +5,17 @@ import (
 	"github.com/alexedwards/argon2id"
 )
 
+// PasswordParams are the argon2id parameters a password is hashed with.
+type PasswordParams = argon2id.Params
+
+var DefaultPasswordParams = *argon2id.DefaultParams
+
 func HashPassword(password string) (string, error) {
-	hashed_password, err := argon2id.CreateHash(password, argon2id.DefaultParams)
+	return HashPasswordWithParams(password, DefaultPasswordParams)
+}
+
+func HashPasswordWithParams(password string, params PasswordParams) (string, error) {
+	hashed_password, err := argon2id.CreateHash(password, &params)
 	if err != nil {
 		return "", errors.New("failed to hash password with argon2id")
 	}
@@ -20,3 +29,18 @@ func CheckPasswordHash(password string, hashed_password string) (bool, error) {
 	}
 	return ok, nil
 }
+
+// NeedsRehash reports whether hashed_password was made with weaker
+// parameters than params. Parallelism is left out: it changes the hash but
+// not the cost, and instances with different CPU counts would otherwise
+// keep rehashing each other's hashes.
+func NeedsRehash(hashed_password string, params PasswordParams) (bool, error) {
+	stored, salt, key, err := argon2id.DecodeHash(hashed_password)
+	if err != nil {
+		return false, errors.New("failed to decode argon2id hash")
+	}
+	return stored.Memory < params.Memory ||
+		stored.Iterations < params.Iterations ||
+		uint32(len(salt)) < params.SaltLength ||
+		uint32(len(key)) < params.KeyLength, nil
+}
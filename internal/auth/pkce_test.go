@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+// from RFC 7636 appendix B
+const (
+	rfcVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	if !VerifyPKCE(rfcVerifier, rfcChallenge) {
+		t.Fatal("expected true, got false")
+	}
+}
+
+func TestVerifyPKCERejectsWrongVerifier(t *testing.T) {
+	if VerifyPKCE(strings.Repeat("a", 43), rfcChallenge) {
+		t.Fatal("expected false, got true")
+	}
+}
+
+func TestVerifyPKCERejectsPlainMethod(t *testing.T) {
+	if VerifyPKCE(rfcVerifier, rfcVerifier) {
+		t.Fatal("expected false, got true")
+	}
+}
+
+func TestVerifyPKCERejectsShortVerifier(t *testing.T) {
+	if VerifyPKCE("short", rfcChallenge) {
+		t.Fatal("expected false, got true")
+	}
+}
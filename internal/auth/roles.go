@@ -0,0 +1,29 @@
+package auth
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// each role can do everything the roles ranked below it can
+var roleRank = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// HasRole reports whether role grants at least the privileges of required.
+// An unknown or empty role grants nothing beyond a plain user's.
+func HasRole(role, required string) bool {
+	rank, ok := roleRank[role]
+	if !ok {
+		rank = roleRank[RoleUser]
+	}
+	return rank >= roleRank[required]
+}
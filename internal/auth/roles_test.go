@@ -0,0 +1,37 @@
+package auth
+
+import "testing"
+
+func TestHasRole(t *testing.T) {
+	cases := []struct {
+		role     string
+		required string
+		want     bool
+	}{
+		{RoleAdmin, RoleAdmin, true},
+		{RoleAdmin, RoleModerator, true},
+		{RoleModerator, RoleModerator, true},
+		{RoleModerator, RoleAdmin, false},
+		{RoleUser, RoleModerator, false},
+		{RoleUser, RoleUser, true},
+		{"", RoleUser, true},
+		{"", RoleModerator, false},
+		{"superuser", RoleAdmin, false},
+	}
+	for _, c := range cases {
+		if got := HasRole(c.role, c.required); got != c.want {
+			t.Errorf("HasRole(%q, %q) = %v, want %v", c.role, c.required, got, c.want)
+		}
+	}
+}
+
+func TestValidRole(t *testing.T) {
+	for _, role := range []string{RoleUser, RoleModerator, RoleAdmin} {
+		if !ValidRole(role) {
+			t.Errorf("expected %q to be valid", role)
+		}
+	}
+	if ValidRole("superuser") {
+		t.Error("expected superuser to be invalid")
+	}
+}
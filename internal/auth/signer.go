@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signer signs the JWTs chirpy issues and supplies the keys to verify them.
+type Signer interface {
+	Sign(claims jwt.Claims) (string, error)
+	Keyfunc(token *jwt.Token) (any, error)
+	// Algorithms lists the only "alg" values a token may use, so a token can't
+	// pick a weaker algorithm than the key was meant for.
+	Algorithms() []string
+}
+
+// HMACSigner signs with a single shared secret (HS256). Anything that needs
+// to verify its tokens must also hold the secret.
+type HMACSigner struct {
+	secret []byte
+}
+
+func NewHMACSigner(secret string) *HMACSigner {
+	return &HMACSigner{secret: []byte(secret)}
+}
+
+func (s *HMACSigner) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.secret)
+}
+
+func (s *HMACSigner) Keyfunc(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return s.secret, nil
+}
+
+func (s *HMACSigner) Algorithms() []string {
+	return []string{jwt.SigningMethodHS256.Alg()}
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// "12345678901234567890" in base32, the SHA-1 key from RFC 6238 appendix B
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, c := range cases {
+		got, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(c.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Fatalf("at %d got %s, want %s", c.unix, got, c.want)
+		}
+	}
+}
+
+func TestValidateTOTPCodeAllowsClockDrift(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	code, err := TOTPCode(secret, TOTPStep(now.Add(-30*time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, ok, err := ValidateTOTPCode(secret, code, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || step != TOTPStep(now)-1 {
+		t.Fatalf("expected code from the previous step to be accepted, got ok=%v step=%d", ok, step)
+	}
+}
+
+func TestValidateTOTPCodeRejectsOldCode(t *testing.T) {
+	now := time.Now()
+	code, err := TOTPCode(rfcSecret, TOTPStep(now.Add(-5*time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok, err := ValidateTOTPCode(rfcSecret, code, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("expected false, got true")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI(rfcSecret, "user@example.com", "Chirpy")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:user@example.com?") {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+	if !strings.Contains(uri, "secret="+rfcSecret) {
+		t.Fatalf("uri is missing the secret: %s", uri)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("unexpected recovery code format %q", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate recovery code %q", code)
+		}
+		seen[code] = true
+	}
+	if len(codes) != 10 {
+		t.Fatalf("got %d codes, want 10", len(codes))
+	}
+}
@@ -7,25 +7,106 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
+with chirp as (
+  insert into chirps (
+    body, user_id, in_reply_to, kind, quote_of
+  ) values (
+    $1, $2, $3, $4, $5
+  ) returning id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of
+), hashtags as (
+  insert into chirp_hashtags (chirp_id, tag, created_at)
+  select chirp.id, tag, chirp.created_at
+  from chirp, unnest($6::text[]) as tag
+), mentions as (
+  insert into chirp_mentions (chirp_id, handle, user_id)
+  select chirp.id, lower(users.handle), users.id
+  from chirp, users
+  where lower(users.handle) = any($7::text[])
+  returning user_id
+), notified as (
+  insert into notifications (user_id, kind, actor_id, chirp_id)
+  select mentions.user_id, 'mention', chirp.user_id, chirp.id
+  from chirp, mentions
+  where mentions.user_id <> chirp.user_id
+)
+select id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of from chirp
+`
+
+type CreateChirpParams struct {
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	Kind      string
+	QuoteOf   uuid.NullUUID
+	Hashtags  []string
+	Mentions  []string
+}
+
+type CreateChirpRow struct {
+	ID         uuid.UUID
+	CreatedAt  sql.NullTime
+	UpdatedAt  sql.NullTime
+	Body       string
+	UserID     uuid.UUID
+	EditedAt   sql.NullTime
+	InReplyTo  uuid.NullUUID
+	ReplyCount int32
+	LikeCount  int32
+	Kind       string
+	RechirpOf  uuid.NullUUID
+	QuoteOf    uuid.NullUUID
+}
+
+func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (CreateChirpRow, error) {
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.Kind,
+		arg.QuoteOf,
+		pq.Array(arg.Hashtags),
+		pq.Array(arg.Mentions),
+	)
+	var i CreateChirpRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.InReplyTo,
+		&i.ReplyCount,
+		&i.LikeCount,
+		&i.Kind,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
 insert into chirps (
-  body, user_id
+  body, user_id, kind, rechirp_of
 ) values (
-  $1, $2
-) returning id, created_at, updated_at, body, user_id
+  '', $1, 'rechirp', $2
+) returning id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of
 `
 
-type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+type CreateRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
 }
 
-func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOf)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -33,6 +114,13 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.EditedAt,
+		&i.InReplyTo,
+		&i.ReplyCount,
+		&i.LikeCount,
+		&i.Kind,
+		&i.RechirpOf,
+		&i.QuoteOf,
 	)
 	return i, err
 }
@@ -51,12 +139,295 @@ func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) error
 	return err
 }
 
-const getAllChirps = `-- name: GetAllChirps :many
-select id, created_at, updated_at, body, user_id from chirps order by created_at
+const deleteChirpByID = `-- name: DeleteChirpByID :execrows
+delete from chirps where id = $1
+`
+
+func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteChirpByID, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteRechirp = `-- name: DeleteRechirp :execrows
+delete from chirps where user_id = $1 and rechirp_of = $2
+`
+
+type DeleteRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRechirp, arg.UserID, arg.RechirpOf)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const editChirp = `-- name: EditChirp :one
+with previous as (
+  select id, user_id, body, created_at, coalesce(edited_at, created_at) as written_at
+  from chirps
+  where id = $1 and user_id = $2
+  for update
+), revision as (
+  insert into chirp_revisions (chirp_id, body, written_at)
+  select id, body, written_at from previous
+), removed_hashtags as (
+  delete from chirp_hashtags
+  where chirp_id = (select id from previous) and tag <> all($3::text[])
+), added_hashtags as (
+  insert into chirp_hashtags (chirp_id, tag, created_at)
+  select previous.id, tag, previous.created_at
+  from previous, unnest($3::text[]) as tag
+  on conflict do nothing
+), removed_mentions as (
+  delete from chirp_mentions
+  where chirp_id = (select id from previous) and handle <> all($4::text[])
+), added_mentions as (
+  -- handles mentioned before keep the user they were resolved to
+  insert into chirp_mentions (chirp_id, handle, user_id)
+  select previous.id, lower(users.handle), users.id
+  from previous, users
+  where lower(users.handle) = any($4::text[])
+  on conflict do nothing
+  returning user_id
+), notified as (
+  insert into notifications (user_id, kind, actor_id, chirp_id)
+  select added_mentions.user_id, 'mention', previous.user_id, previous.id
+  from previous, added_mentions
+  where added_mentions.user_id <> previous.user_id
+    -- toggling a mention off and on again mustn't notify anyone twice
+    and not exists (
+      select 1 from notifications
+      where notifications.chirp_id = previous.id
+        and notifications.user_id = added_mentions.user_id
+        and notifications.kind = 'mention'
+    )
+)
+update chirps
+set body = $5, edited_at = now(), updated_at = now()
+from previous
+where chirps.id = previous.id
+returning chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.in_reply_to, chirps.reply_count, chirps.like_count, chirps.kind, chirps.rechirp_of, chirps.quote_of
+`
+
+type EditChirpParams struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	Hashtags []string
+	Mentions []string
+	Body     string
+}
+
+func (q *Queries) EditChirp(ctx context.Context, arg EditChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, editChirp,
+		arg.ID,
+		arg.UserID,
+		pq.Array(arg.Hashtags),
+		pq.Array(arg.Mentions),
+		arg.Body,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.InReplyTo,
+		&i.ReplyCount,
+		&i.LikeCount,
+		&i.Kind,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const getChirp = `-- name: GetChirp :one
+select id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of from chirps where id = $1 limit 1
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.InReplyTo,
+		&i.ReplyCount,
+		&i.LikeCount,
+		&i.Kind,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+with recursive ancestors as (
+  select parent.id, parent.created_at, parent.updated_at, parent.body, parent.user_id, parent.edited_at, parent.in_reply_to, parent.reply_count, parent.like_count, parent.kind, parent.rechirp_of, parent.quote_of, 1 as depth
+  from chirps parent
+  join chirps child on child.in_reply_to = parent.id
+  where child.id = $1
+  union all
+  select parent.id, parent.created_at, parent.updated_at, parent.body, parent.user_id, parent.edited_at, parent.in_reply_to, parent.reply_count, parent.like_count, parent.kind, parent.rechirp_of, parent.quote_of, ancestors.depth + 1
+  from chirps parent
+  join ancestors on ancestors.in_reply_to = parent.id
+)
+select id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of
+from ancestors
+order by depth desc
+`
+
+type GetChirpAncestorsRow struct {
+	ID         uuid.UUID
+	CreatedAt  sql.NullTime
+	UpdatedAt  sql.NullTime
+	Body       string
+	UserID     uuid.UUID
+	EditedAt   sql.NullTime
+	InReplyTo  uuid.NullUUID
+	ReplyCount int32
+	LikeCount  int32
+	Kind       string
+	RechirpOf  uuid.NullUUID
+	QuoteOf    uuid.NullUUID
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, id uuid.UUID) ([]GetChirpAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpAncestorsRow
+	for rows.Next() {
+		var i GetChirpAncestorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+with recursive descendants as (
+  select chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.in_reply_to, chirps.reply_count, chirps.like_count, chirps.kind, chirps.rechirp_of, chirps.quote_of, 1 as depth,
+    array[to_char(created_at, 'YYYYMMDDHH24MISSUS') || id::text] as path
+  from chirps
+  where in_reply_to = $1
+  union all
+  select chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.in_reply_to, chirps.reply_count, chirps.like_count, chirps.kind, chirps.rechirp_of, chirps.quote_of, descendants.depth + 1,
+    descendants.path || (to_char(chirps.created_at, 'YYYYMMDDHH24MISSUS') || chirps.id::text)
+  from chirps
+  join descendants on chirps.in_reply_to = descendants.id
+)
+select id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of, depth
+from descendants
+where $2::uuid is null
+  or path > (select path from descendants where id = $2::uuid)
+order by path
+limit $3
+`
+
+type GetChirpDescendantsParams struct {
+	InReplyTo uuid.NullUUID
+	AfterID   uuid.NullUUID
+	PageSize  int32
+}
+
+type GetChirpDescendantsRow struct {
+	ID         uuid.UUID
+	CreatedAt  sql.NullTime
+	UpdatedAt  sql.NullTime
+	Body       string
+	UserID     uuid.UUID
+	EditedAt   sql.NullTime
+	InReplyTo  uuid.NullUUID
+	ReplyCount int32
+	LikeCount  int32
+	Kind       string
+	RechirpOf  uuid.NullUUID
+	QuoteOf    uuid.NullUUID
+	Depth      int32
+}
+
+// Pages are keyed on the path of the last reply on the previous page, so
+// replies posted while a client pages don't shift the rest.
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]GetChirpDescendantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants, arg.InReplyTo, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpDescendantsRow
+	for rows.Next() {
+		var i GetChirpDescendantsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+select id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of from chirps where id = any($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
@@ -70,6 +441,13 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.EditedAt,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
 		); err != nil {
 			return nil, err
 		}
@@ -84,29 +462,122 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirp = `-- name: GetChirp :one
-select id, created_at, updated_at, body, user_id from chirps where id = $1 limit 1
+const listChirpRevisions = `-- name: ListChirpRevisions :many
+select id, chirp_id, body, written_at, replaced_at from chirp_revisions
+where chirp_id = $1
+order by replaced_at
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
-	var i Chirp
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Body,
-		&i.UserID,
+func (q *Queries) ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]ChirpRevision, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpRevisions, chirpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpRevision
+	for rows.Next() {
+		var i ChirpRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChirpID,
+			&i.Body,
+			&i.WrittenAt,
+			&i.ReplacedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirps = `-- name: ListChirps :many
+select id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of from chirps
+where ($1::uuid is null or user_id = $1::uuid)
+  and ($2::timestamp is null
+    or (created_at, id) > ($2::timestamp, $3::uuid))
+order by created_at, id
+limit $4
+`
+
+type ListChirpsParams struct {
+	AuthorID       uuid.NullUUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	PageSize       int32
+}
+
+// Oldest first, optionally by one author.
+func (q *Queries) ListChirps(ctx context.Context, arg ListChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirps,
+		arg.AuthorID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
 	)
-	return i, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
-select id, created_at, updated_at, body, user_id from chirps where user_id = $1 order by created_at
+const listChirpsNewestFirst = `-- name: ListChirpsNewestFirst :many
+select id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of from chirps
+where ($1::uuid is null or user_id = $1::uuid)
+  and ($2::timestamp is null
+    or (created_at, id) < ($2::timestamp, $3::uuid))
+order by created_at desc, id desc
+limit $4
 `
 
-func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor, userID)
+type ListChirpsNewestFirstParams struct {
+	AuthorID        uuid.NullUUID
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListChirpsNewestFirst(ctx context.Context, arg ListChirpsNewestFirstParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsNewestFirst,
+		arg.AuthorID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -120,6 +591,13 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.EditedAt,
+			&i.InReplyTo,
+			&i.ReplyCount,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
 		); err != nil {
 			return nil, err
 		}
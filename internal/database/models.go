@@ -6,32 +6,231 @@ package database
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+	ExpiresAt  sql.NullTime
+	RevokedAt  sql.NullTime
+	ClientID   sql.NullString
+}
+
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	FolderID  uuid.NullUUID
+	CreatedAt time.Time
+}
+
+type BookmarkFolder struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID         uuid.UUID
+	CreatedAt  sql.NullTime
+	UpdatedAt  sql.NullTime
+	Body       string
+	UserID     uuid.UUID
+	EditedAt   sql.NullTime
+	InReplyTo  uuid.NullUUID
+	ReplyCount int32
+	LikeCount  int32
+	Kind       string
+	RechirpOf  uuid.NullUUID
+	QuoteOf    uuid.NullUUID
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID uuid.UUID
+	Handle  string
+	UserID  uuid.UUID
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	WrittenAt  time.Time
+	ReplacedAt time.Time
+}
+
+type DeletedUser struct {
+	ID                  uuid.UUID
+	EmailHash           string
+	CreatedAt           sql.NullTime
+	DeletionRequestedAt sql.NullTime
+	DeletedAt           time.Time
+}
+
+type EmailVerification struct {
 	ID        uuid.UUID
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
 	CreatedAt sql.NullTime
-	UpdatedAt sql.NullTime
-	Body      string
+	Email     string
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginAttempt struct {
+	Key           string
+	Failures      int32
+	LastFailureAt time.Time
+	LockedUntil   sql.NullTime
+}
+
+type MagicLinkToken struct {
+	TokenHash string
 	UserID    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+	Email     string
 }
 
-type RefreshToken struct {
-	Token     string
+type Notification struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Kind      string
+	ActorID   uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+	ReadAt    sql.NullTime
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string
+	ClientID      string
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scopes        []string
+	CodeChallenge string
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+	CreatedAt     time.Time
+}
+
+type OauthClient struct {
+	ID           string
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+	CreatedAt    time.Time
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt sql.NullTime
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
-	ExpiresAt sql.NullTime
-	RevokedAt sql.NullTime
+	CodeHash  string
+	UsedAt    sql.NullTime
 	CreatedAt sql.NullTime
-	UpdatedAt sql.NullTime
+}
+
+type RefreshToken struct {
+	Token       string
+	UserID      uuid.UUID
+	ExpiresAt   sql.NullTime
+	RevokedAt   sql.NullTime
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
+}
+
+type Session struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	UserAgent  sql.NullString
+	IpAddress  sql.NullString
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	RevokedAt  sql.NullTime
+}
+
+type TotpCredential struct {
+	UserID       uuid.UUID
+	Secret       string
+	EnabledAt    sql.NullTime
+	LastUsedStep int64
+	CreatedAt    sql.NullTime
+}
+
+type TrendingHashtag struct {
+	Tag        string
+	Score      float64
+	ChirpCount int32
+	ComputedAt time.Time
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      sql.NullTime
-	UpdatedAt      sql.NullTime
-	Email          string
-	HashedPassword string
-	IsChirpyRed    sql.NullBool
+	ID                  uuid.UUID
+	CreatedAt           sql.NullTime
+	UpdatedAt           sql.NullTime
+	Email               string
+	HashedPassword      string
+	IsChirpyRed         sql.NullBool
+	EmailVerifiedAt     sql.NullTime
+	Role                string
+	DeletionRequestedAt sql.NullTime
+	DeleteAfter         sql.NullTime
+	Handle              sql.NullString
+}
+
+type WebauthnChallenge struct {
+	ID        uuid.UUID
+	UserID    uuid.NullUUID
+	Challenge []byte
+	Ceremony  string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type WebauthnCredential struct {
+	ID         []byte
+	UserID     uuid.UUID
+	Name       string
+	PublicKey  []byte
+	SignCount  int64
+	Transports []string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
 }
@@ -12,53 +12,95 @@ import (
 	"github.com/google/uuid"
 )
 
-const createRefreshToken = `-- name: CreateRefreshToken :one
+const createRefreshTokenInFamily = `-- name: CreateRefreshTokenInFamily :one
 insert into refresh_tokens (
-  token, user_id
+  token, user_id, family_id, parent_token
 ) values (
-  $1, $2
+  $1, $2, $3, $4
 ) returning token
 `
 
-type CreateRefreshTokenParams struct {
-	Token  string
-	UserID uuid.UUID
+type CreateRefreshTokenInFamilyParams struct {
+	Token       string
+	UserID      uuid.UUID
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
 }
 
-func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (string, error) {
-	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.UserID)
+func (q *Queries) CreateRefreshTokenInFamily(ctx context.Context, arg CreateRefreshTokenInFamilyParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, createRefreshTokenInFamily,
+		arg.Token,
+		arg.UserID,
+		arg.FamilyID,
+		arg.ParentToken,
+	)
 	var token string
 	err := row.Scan(&token)
 	return token, err
 }
 
-const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
+const getRefreshToken = `-- name: GetRefreshToken :one
+select token, user_id, expires_at, revoked_at, created_at, updated_at, family_id, parent_token from refresh_tokens where token = $1 limit 1
+`
+
+func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshToken, token)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+	)
+	return i, err
+}
+
+const revokeAllRefreshTokensForUser = `-- name: RevokeAllRefreshTokensForUser :exec
 update refresh_tokens
 set
   revoked_at = now(),
   updated_at = now()
-where token = $1
-returning token
+where user_id = $1 and revoked_at is null
 `
 
-func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
-	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
+func (q *Queries) RevokeAllRefreshTokensForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeAllRefreshTokensForUser, userID)
 	return err
 }
 
-const validateRefreshToken = `-- name: ValidateRefreshToken :one
-select (revoked_at is null and now() < expires_at) as valid, user_id
-from refresh_tokens where token = $1 limit 1
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+update refresh_tokens
+set
+  revoked_at = now(),
+  updated_at = now()
+where family_id = $1 and user_id = $2 and revoked_at is null
 `
 
-type ValidateRefreshTokenRow struct {
-	Valid  sql.NullBool
-	UserID uuid.UUID
+type RevokeRefreshTokenFamilyParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
 }
 
-func (q *Queries) ValidateRefreshToken(ctx context.Context, token string) (ValidateRefreshTokenRow, error) {
-	row := q.db.QueryRowContext(ctx, validateRefreshToken, token)
-	var i ValidateRefreshTokenRow
-	err := row.Scan(&i.Valid, &i.UserID)
-	return i, err
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, arg RevokeRefreshTokenFamilyParams) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, arg.FamilyID, arg.UserID)
+	return err
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+update refresh_tokens
+set
+  revoked_at = now(),
+  updated_at = now()
+where token = $1 and revoked_at is null
+returning token
+`
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, token string) (string, error) {
+	row := q.db.QueryRowContext(ctx, rotateRefreshToken, token)
+	err := row.Scan(&token)
+	return token, err
 }
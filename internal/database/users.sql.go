@@ -15,15 +15,17 @@ import (
 const createUser = `-- name: CreateUser :one
 insert into users (
   email,
-  hashed_password
+  hashed_password,
+  handle
 ) values (
-  $1, $2
-) returning id, created_at, updated_at, email, is_chirpy_red
+  $1, $2, $3
+) returning id, created_at, updated_at, email, is_chirpy_red, handle
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 type CreateUserRow struct {
@@ -32,10 +34,11 @@ type CreateUserRow struct {
 	UpdatedAt   sql.NullTime
 	Email       string
 	IsChirpyRed sql.NullBool
+	Handle      sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i CreateUserRow
 	err := row.Scan(
 		&i.ID,
@@ -43,6 +46,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
 	)
 	return i, err
 }
@@ -57,7 +61,7 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 }
 
 const getUser = `-- name: GetUser :one
-select id, created_at, updated_at, email, hashed_password, is_chirpy_red from users where id = $1 limit 1
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at, role, deletion_requested_at, delete_after, handle from users where id = $1 limit 1
 `
 
 func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
@@ -70,12 +74,17 @@ func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.Role,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-select id, created_at, updated_at, email, hashed_password, is_chirpy_red from users where email = $1 limit 1
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at, role, deletion_requested_at, delete_after, handle from users where email = $1 limit 1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -88,15 +97,82 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.Role,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+	)
+	return i, err
+}
+
+const promoteFirstAdmin = `-- name: PromoteFirstAdmin :one
+update users
+set role = 'admin'
+where email = $1
+  and not exists (select 1 from users where role = 'admin')
+returning id
+`
+
+func (q *Queries) PromoteFirstAdmin(ctx context.Context, email string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, promoteFirstAdmin, email)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+update users
+set role = $2
+where id = $1
+returning id, created_at, updated_at, email, is_chirpy_red, role, handle
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+type SetUserRoleRow struct {
+	ID          uuid.UUID
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+	Email       string
+	IsChirpyRed sql.NullBool
+	Role        string
+	Handle      sql.NullString
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (SetUserRoleRow, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i SetUserRoleRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.Handle,
 	)
 	return i, err
 }
 
 const updateUserEmail = `-- name: UpdateUserEmail :one
+with discarded as (
+  delete from email_verifications
+  where user_id = $1 and used_at is null
+), discarded_links as (
+  delete from magic_link_tokens
+  where user_id = $1 and used_at is null
+), discarded_resets as (
+  delete from password_reset_tokens
+  where user_id = $1 and used_at is null
+)
 update users
-set email = $2
+set email = $2, email_verified_at = null
 where id = $1
-returning id, created_at, updated_at, email, is_chirpy_red
+returning id, created_at, updated_at, email, is_chirpy_red, handle
 `
 
 type UpdateUserEmailParams struct {
@@ -110,8 +186,11 @@ type UpdateUserEmailRow struct {
 	UpdatedAt   sql.NullTime
 	Email       string
 	IsChirpyRed sql.NullBool
+	Handle      sql.NullString
 }
 
+// Verifications, sign-in links and password resets sent to the old address
+// are thrown away with it.
 func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (UpdateUserEmailRow, error) {
 	row := q.db.QueryRowContext(ctx, updateUserEmail, arg.ID, arg.Email)
 	var i UpdateUserEmailRow
@@ -121,6 +200,42 @@ func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
+	)
+	return i, err
+}
+
+const updateUserHandle = `-- name: UpdateUserHandle :one
+update users
+set handle = $2
+where id = $1
+returning id, created_at, updated_at, email, is_chirpy_red, handle
+`
+
+type UpdateUserHandleParams struct {
+	ID     uuid.UUID
+	Handle sql.NullString
+}
+
+type UpdateUserHandleRow struct {
+	ID          uuid.UUID
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+	Email       string
+	IsChirpyRed sql.NullBool
+	Handle      sql.NullString
+}
+
+func (q *Queries) UpdateUserHandle(ctx context.Context, arg UpdateUserHandleParams) (UpdateUserHandleRow, error) {
+	row := q.db.QueryRowContext(ctx, updateUserHandle, arg.ID, arg.Handle)
+	var i UpdateUserHandleRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Handle,
 	)
 	return i, err
 }
@@ -129,7 +244,7 @@ const updateUserPassword = `-- name: UpdateUserPassword :one
 update users
 set hashed_password = $2
 where id = $1
-returning id, created_at, updated_at, email, is_chirpy_red
+returning id, created_at, updated_at, email, is_chirpy_red, handle
 `
 
 type UpdateUserPasswordParams struct {
@@ -143,6 +258,7 @@ type UpdateUserPasswordRow struct {
 	UpdatedAt   sql.NullTime
 	Email       string
 	IsChirpyRed sql.NullBool
+	Handle      sql.NullString
 }
 
 func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (UpdateUserPasswordRow, error) {
@@ -154,6 +270,7 @@ func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPassword
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
 	)
 	return i, err
 }
@@ -162,7 +279,7 @@ const upgradeUser = `-- name: UpgradeUser :one
 update users
 set is_chirpy_red = true
 where id = $1
-returning id, created_at, updated_at, email, is_chirpy_red
+returning id, created_at, updated_at, email, is_chirpy_red, handle
 `
 
 type UpgradeUserRow struct {
@@ -171,6 +288,7 @@ type UpgradeUserRow struct {
 	UpdatedAt   sql.NullTime
 	Email       string
 	IsChirpyRed sql.NullBool
+	Handle      sql.NullString
 }
 
 func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) (UpgradeUserRow, error) {
@@ -182,6 +300,65 @@ func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) (UpgradeUserRow
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
+	)
+	return i, err
+}
+
+const upgradeUserPasswordHash = `-- name: UpgradeUserPasswordHash :execrows
+update users
+set hashed_password = $1
+where id = $2 and hashed_password = $3
+`
+
+type UpgradeUserPasswordHashParams struct {
+	NewHash string
+	ID      uuid.UUID
+	OldHash string
+}
+
+func (q *Queries) UpgradeUserPasswordHash(ctx context.Context, arg UpgradeUserPasswordHashParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, upgradeUserPasswordHash, arg.NewHash, arg.ID, arg.OldHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+update users
+set email_verified_at = coalesce(email_verified_at, now())
+where id = $1 and email = $2
+returning id, created_at, updated_at, email, is_chirpy_red, handle, email_verified_at
+`
+
+type VerifyUserEmailParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+type VerifyUserEmailRow struct {
+	ID              uuid.UUID
+	CreatedAt       sql.NullTime
+	UpdatedAt       sql.NullTime
+	Email           string
+	IsChirpyRed     sql.NullBool
+	Handle          sql.NullString
+	EmailVerifiedAt sql.NullTime
+}
+
+// Only verifies email if it is still the account's address.
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (VerifyUserEmailRow, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.ID, arg.Email)
+	var i VerifyUserEmailRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,233 @@
+// Package entities finds the parts of a chirp body that clients show as
+// links: hashtags, @mentions and URLs.
+package entities
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	TypeHashtag = "hashtag"
+	TypeMention = "mention"
+	TypeURL     = "url"
+
+	MaxHandleLength = 15
+)
+
+// Entity is a span of a chirp body. Start and End are byte offsets, so
+// body[Start:End] is the entity as it was written. RuneStart and RuneEnd
+// are the same span counted in runes, for clients whose strings aren't
+// UTF-8.
+type Entity struct {
+	Type string
+	// the normalized entity: for hashtags, the lower-cased tag without #;
+	// for mentions, the lower-cased handle without @; for URLs, the URL
+	Text      string
+	Start     int
+	End       int
+	RuneStart int
+	RuneEnd   int
+}
+
+// Parse returns the entities in body, in the order they appear.
+//
+// A hashtag is # (or the full-width ＃) followed by letters, marks, digits
+// and underscores in any script, with at least one letter, so #1 is not a
+// hashtag but #2024年 is. It must not be glued to the word before it, as
+// in a#b or the HTML entity &#39;.
+//
+// A mention is @ (or ＠) followed by a handle: up to MaxHandleLength ASCII
+// letters, digits and underscores. It must not be glued to the word before
+// it either, so email addresses aren't mentions.
+//
+// A URL starts with http:// or https:// and runs to the next space, less
+// any punctuation that ends the sentence around it. Hashtags and mentions
+// inside URLs are part of the URL.
+func Parse(body string) []Entity {
+	entities := []Entity{}
+	prev := ' '
+	runes := 0
+	for i := 0; i < len(body); {
+		if entity, ok := parseAt(body, i, prev); ok {
+			n := utf8.RuneCountInString(body[entity.Start:entity.End])
+			entity.RuneStart, entity.RuneEnd = runes, runes+n
+			entities = append(entities, entity)
+			prev, _ = utf8.DecodeLastRuneInString(body[:entity.End])
+			runes += n
+			i = entity.End
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(body[i:])
+		prev = r
+		runes++
+		i += size
+	}
+	return entities
+}
+
+// parseAt returns the entity starting at body[i:], if there is one. prev is
+// the rune before it.
+func parseAt(body string, i int, prev rune) (Entity, bool) {
+	if isTagRune(prev) {
+		return Entity{}, false
+	}
+	r, size := utf8.DecodeRuneInString(body[i:])
+	switch {
+	case isHashSign(r) && prev != '&':
+		end := scanTag(body, i+size)
+		if tag := body[i+size : end]; isTag(tag) {
+			return Entity{Type: TypeHashtag, Text: strings.ToLower(tag), Start: i, End: end}, true
+		}
+	case isAtSign(r):
+		end := scanHandle(body, i+size)
+		if handle := body[i+size : end]; ValidHandle(handle) && !gluedToNext(body, end) {
+			return Entity{Type: TypeMention, Text: strings.ToLower(handle), Start: i, End: end}, true
+		}
+	case r == 'h' || r == 'H':
+		if end := scanURL(body, i); end > i {
+			return Entity{Type: TypeURL, Text: body[i:end], Start: i, End: end}, true
+		}
+	}
+	return Entity{}, false
+}
+
+// Hashtags returns the distinct normalized hashtags in body, in the order
+// they are first used.
+func Hashtags(body string) []string {
+	return distinct(body, TypeHashtag)
+}
+
+// Mentions returns the distinct lower-cased handles mentioned in body, in
+// the order they are first mentioned.
+func Mentions(body string) []string {
+	return distinct(body, TypeMention)
+}
+
+func distinct(body, entityType string) []string {
+	texts := []string{}
+	seen := map[string]bool{}
+	for _, entity := range Parse(body) {
+		if entity.Type != entityType || seen[entity.Text] {
+			continue
+		}
+		seen[entity.Text] = true
+		texts = append(texts, entity.Text)
+	}
+	return texts
+}
+
+// NormalizeHashtag returns tag, with or without its #, the way Parse would
+// store it. ok is false if tag isn't a hashtag at all.
+func NormalizeHashtag(tag string) (normalized string, ok bool) {
+	if r, size := utf8.DecodeRuneInString(tag); isHashSign(r) {
+		tag = tag[size:]
+	}
+	if !isTag(tag) || scanTag(tag, 0) != len(tag) {
+		return "", false
+	}
+	return strings.ToLower(tag), true
+}
+
+// ValidHandle reports whether handle, without its @, can be mentioned.
+func ValidHandle(handle string) bool {
+	return handle != "" && len(handle) <= MaxHandleLength && scanHandle(handle, 0) == len(handle)
+}
+
+func isHashSign(r rune) bool {
+	return r == '#' || r == '＃'
+}
+
+func isAtSign(r rune) bool {
+	return r == '@' || r == '＠'
+}
+
+// isTagRune reports whether r can be part of a hashtag. Zero-width joiners
+// are allowed because some scripts, like Persian and the Indic scripts,
+// need them inside words.
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.Is(unicode.Nd, r) ||
+		r == '_' || r == '\u200c' || r == '\u200d'
+}
+
+func isHandleByte(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || b == '_'
+}
+
+// scanTag returns where the run of tag runes starting at body[start:] ends.
+func scanTag(body string, start int) int {
+	end := start
+	for end < len(body) {
+		r, size := utf8.DecodeRuneInString(body[end:])
+		if !isTagRune(r) {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+func scanHandle(body string, start int) int {
+	end := start
+	for end < len(body) && isHandleByte(body[end]) {
+		end++
+	}
+	return end
+}
+
+// gluedToNext reports whether body[i:] carries on the word before i, which
+// makes a handle that ends at i too long or part of an email address.
+func gluedToNext(body string, i int) bool {
+	if i == len(body) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(body[i:])
+	return isTagRune(r) || isAtSign(r)
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func isTag(tag string) bool {
+	return strings.IndexFunc(tag, unicode.IsLetter) >= 0
+}
+
+// scanURL returns where the URL starting at body[start:] ends, or start if
+// there isn't one.
+func scanURL(body string, start int) int {
+	var scheme int
+	switch {
+	case hasPrefixFold(body[start:], "https://"):
+		scheme = len("https://")
+	case hasPrefixFold(body[start:], "http://"):
+		scheme = len("http://")
+	default:
+		return start
+	}
+
+	end := start + scheme
+	for end < len(body) {
+		r, size := utf8.DecodeRuneInString(body[end:])
+		if unicode.IsSpace(r) || r == '<' || r == '>' || r == '"' {
+			break
+		}
+		end += size
+	}
+	// leave out punctuation after the URL, and a closing parenthesis
+	// unless the URL opened it, as in Wikipedia links
+	for end > start+scheme {
+		last := body[end-1]
+		if strings.IndexByte(".,:;!?'*", last) >= 0 ||
+			last == ')' && strings.Count(body[start:end], "(") < strings.Count(body[start:end], ")") {
+			end--
+			continue
+		}
+		break
+	}
+	if end == start+scheme {
+		return start
+	}
+	return end
+}
@@ -0,0 +1,155 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"no tags here", []string{}},
+		{"#Go is fun", []string{"go"}},
+		{"I like #go and #Go and #GO", []string{"go"}},
+		{"#golang, #rust!", []string{"golang", "rust"}},
+		{"(#parens) and \"#quotes\"", []string{"parens", "quotes"}},
+		{"snake #snake_case", []string{"snake_case"}},
+		{"#1 and #42 are not tags", []string{}},
+		{"but #2024年 and #web3 are", []string{"2024年", "web3"}},
+		{"a#b is not a tag", []string{}},
+		{"&#39; is an entity", []string{}},
+		{"# alone", []string{}},
+		{"#Ünïcödé #日本語 #Привет #café", []string{"ünïcödé", "日本語", "привет", "café"}},
+		{"full width ＃Tag", []string{"tag"}},
+		{"#a#b", []string{"a"}},
+		{"ends with #tag", []string{"tag"}},
+	}
+	for _, tt := range tests {
+		if got := Hashtags(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Hashtags(%q) = %q, expected %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestParseOffsets(t *testing.T) {
+	body := "héllo #Wörld!"
+	got := Parse(body)
+	if len(got) != 1 {
+		t.Fatalf("expected one entity, got %+v", got)
+	}
+	e := got[0]
+	if e.Type != TypeHashtag || e.Text != "wörld" || body[e.Start:e.End] != "#Wörld" {
+		t.Fatalf("unexpected entity %+v", e)
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	for tag, want := range map[string]string{
+		"Go":        "go",
+		"#Go":       "go",
+		"＃Café":     "café",
+		"web_3":     "web_3",
+		"日本語":       "日本語",
+		"":          "",
+		"#":         "",
+		"123":       "",
+		"two words": "",
+		"#a#b":      "",
+	} {
+		got, ok := NormalizeHashtag(tag)
+		if got != want || ok != (want != "") {
+			t.Errorf("NormalizeHashtag(%q) = %q, %v, expected %q", tag, got, ok, want)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"hi @Alice and @bob_2", []string{"alice", "bob_2"}},
+		{"@alice @ALICE", []string{"alice"}},
+		{"mail me at me@example.com", []string{}},
+		{"@alice@example.com", []string{}},
+		{"@this_handle_is_too_long", []string{}},
+		{"@sixteen_chars_xx", []string{}},
+		{"@exactly15chars", []string{"exactly15chars"}},
+		{"@alicé", []string{}},
+		{"(@alice), ＠bob!", []string{"alice", "bob"}},
+		{"@ alone", []string{}},
+	}
+	for _, tt := range tests {
+		if got := Mentions(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Mentions(%q) = %q, expected %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestParseURLs(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"see https://example.com/a?b=c#d.", []string{"https://example.com/a?b=c#d"}},
+		{"(http://example.com)", []string{"http://example.com"}},
+		{"https://en.wikipedia.org/wiki/Go_(language)!", []string{"https://en.wikipedia.org/wiki/Go_(language)"}},
+		{"HTTPS://EXAMPLE.COM, ok", []string{"HTTPS://EXAMPLE.COM"}},
+		{"https:// is not a URL, nor is ftp://example.com", []string{}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, e := range Parse(tt.body) {
+			if e.Type != TypeURL {
+				t.Errorf("Parse(%q): unexpected %s %q", tt.body, e.Type, e.Text)
+				continue
+			}
+			got = append(got, e.Text)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("URLs in %q = %q, expected %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestParseRuneOffsets(t *testing.T) {
+	body := "日本 @Bob #東京 https://例え.jp/ @carol"
+	want := []Entity{
+		{Type: TypeMention, Text: "bob", RuneStart: 3, RuneEnd: 7},
+		{Type: TypeHashtag, Text: "東京", RuneStart: 8, RuneEnd: 11},
+		{Type: TypeURL, Text: "https://例え.jp/", RuneStart: 12, RuneEnd: 26},
+		{Type: TypeMention, Text: "carol", RuneStart: 27, RuneEnd: 33},
+	}
+	got := Parse(body)
+	if len(got) != len(want) {
+		t.Fatalf("expected %d entities, got %+v", len(want), got)
+	}
+	runes := []rune(body)
+	for i, e := range got {
+		w := want[i]
+		if e.Type != w.Type || e.Text != w.Text || e.RuneStart != w.RuneStart || e.RuneEnd != w.RuneEnd {
+			t.Errorf("entity %d: expected %+v, got %+v", i, w, e)
+		}
+		if body[e.Start:e.End] != string(runes[e.RuneStart:e.RuneEnd]) {
+			t.Errorf("entity %d: byte and rune spans differ: %+v", i, e)
+		}
+	}
+}
+
+func TestValidHandle(t *testing.T) {
+	for handle, want := range map[string]bool{
+		"alice":            true,
+		"Bob_42":           true,
+		"":                 false,
+		"@alice":           false,
+		"has space":        false,
+		"josé":             false,
+		"sixteen_chars_xx": false,
+	} {
+		if got := ValidHandle(handle); got != want {
+			t.Errorf("ValidHandle(%q) = %v, expected %v", handle, got, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMemoryMailerKeepsMessages(t *testing.T) {
+	m := &MemoryMailer{}
+	msg := Message{To: "user@example.com", Subject: "hello", Body: "hi there"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	got := m.Messages()
+	if len(got) != 1 || got[0] != msg {
+		t.Fatalf("got %v, want [%v]", got, msg)
+	}
+}
+
+func TestFileMailerWritesMessage(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{Dir: dir, From: "chirpy@example.com"}
+	msg := Message{To: "user@example.com", Subject: "hello", Body: "hi there"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d files, want 1", len(entries))
+	}
+	dat, err := os.ReadFile(dir + "/" + entries[0].Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"To: user@example.com", "Subject: hello", "hi there"} {
+		if !strings.Contains(string(dat), want) {
+			t.Fatalf("message is missing %q:\n%s", want, dat)
+		}
+	}
+}
+
+func TestRejectsHeaderInjection(t *testing.T) {
+	m := &MemoryMailer{}
+	err := m.Send(context.Background(), Message{To: "user@example.com\r\nBcc: evil@example.com", Subject: "hi"})
+	if err == nil {
+		t.Fatalf("Expected an error, got nil")
+	}
+}
@@ -0,0 +1,144 @@
+// Package pagination implements keyset pagination for lists ordered by
+// creation time. Clients get an opaque cursor for the next page instead of
+// an offset, so pages stay fast however deep they go and don't skip or
+// repeat items when new ones are added.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of the last item on a page. The ID breaks ties
+// between items created at the same time.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Encode returns the cursor in the opaque form clients send back.
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a cursor made by Encode.
+func Decode(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	c := Cursor{}
+	if c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	if c.ID, err = uuid.Parse(id); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// RankedCursor is the position of the last item on a page of a list ordered
+// by a score, such as search relevance, and then by creation time.
+type RankedCursor struct {
+	Rank float32
+	Cursor
+}
+
+// Encode returns the cursor in the opaque form clients send back.
+func (c RankedCursor) Encode() string {
+	raw := strconv.FormatFloat(float64(c.Rank), 'g', -1, 32) + "|" + c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeRanked parses a cursor made by RankedCursor.Encode.
+func DecodeRanked(s string) (RankedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return RankedCursor{}, ErrInvalidCursor
+	}
+	rank, rest, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return RankedCursor{}, ErrInvalidCursor
+	}
+	r, err := strconv.ParseFloat(rank, 32)
+	if err != nil {
+		return RankedCursor{}, ErrInvalidCursor
+	}
+	c, err := Decode(base64.RawURLEncoding.EncodeToString([]byte(rest)))
+	if err != nil {
+		return RankedCursor{}, err
+	}
+	return RankedCursor{Rank: float32(r), Cursor: c}, nil
+}
+
+// Params are the paging query parameters of a list request.
+type Params struct {
+	Limit int
+	// nil for the first page
+	After *Cursor
+}
+
+// ParseParams reads the limit and cursor query parameters. limit defaults
+// to defaultLimit and may not exceed maxLimit.
+func ParseParams(query url.Values, defaultLimit, maxLimit int) (Params, error) {
+	limit, err := ParseLimit(query, defaultLimit, maxLimit)
+	if err != nil {
+		return Params{}, err
+	}
+	p := Params{Limit: limit}
+	if s := query.Get("cursor"); s != "" {
+		c, err := Decode(s)
+		if err != nil {
+			return Params{}, err
+		}
+		p.After = &c
+	}
+	return p, nil
+}
+
+// ParseLimit reads just the limit query parameter, for lists with their
+// own kind of cursor.
+func ParseLimit(query url.Values, defaultLimit, maxLimit int) (int, error) {
+	s := query.Get("limit")
+	if s == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 || limit > maxLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	return limit, nil
+}
+
+// Trim cuts a page fetched with one extra item, to find out whether there
+// is another page, down to limit. It reports whether there is more.
+func Trim[T any](items []T, limit int) ([]T, bool) {
+	if len(items) > limit {
+		return items[:limit], true
+	}
+	return items, false
+}
+
+// SetNextLink adds an RFC 8288 Link header pointing at the page that
+// starts after next, keeping the request's other query parameters.
+func SetNextLink(w http.ResponseWriter, req *http.Request, next interface{ Encode() string }) {
+	query := req.URL.Query()
+	query.Set("cursor", next.Encode())
+	link := url.URL{Path: req.URL.Path, RawQuery: query.Encode()}
+	w.Header().Add("Link", "<"+link.String()+`>; rel="next"`)
+}
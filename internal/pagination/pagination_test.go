@@ -0,0 +1,92 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{
+		CreatedAt: time.Date(2026, 3, 14, 15, 9, 26, 535897000, time.UTC),
+		ID:        uuid.New(),
+	}
+	got, err := Decode(c.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID {
+		t.Fatalf("expected %+v, got %+v", c, got)
+	}
+}
+
+func TestDecodeRejectsGarbage(t *testing.T) {
+	for _, s := range []string{"", "not base64!", "bm8tc2VwYXJhdG9y", Cursor{}.Encode()[:10]} {
+		if _, err := Decode(s); err == nil {
+			t.Errorf("Decode(%q): expected an error", s)
+		}
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	p, err := ParseParams(url.Values{}, 20, 100)
+	if err != nil || p.Limit != 20 || p.After != nil {
+		t.Fatalf("unexpected defaults %+v, %v", p, err)
+	}
+	for _, limit := range []string{"0", "101", "-1", "ten"} {
+		if _, err := ParseParams(url.Values{"limit": {limit}}, 20, 100); err == nil {
+			t.Errorf("limit %q: expected an error", limit)
+		}
+	}
+	c := Cursor{CreatedAt: time.Now().UTC(), ID: uuid.New()}
+	p, err = ParseParams(url.Values{"limit": {"5"}, "cursor": {c.Encode()}}, 20, 100)
+	if err != nil || p.Limit != 5 || p.After == nil || p.After.ID != c.ID {
+		t.Fatalf("unexpected params %+v, %v", p, err)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	items, more := Trim([]int{1, 2, 3}, 2)
+	if len(items) != 2 || !more {
+		t.Fatalf("expected 2 items and more, got %v, %v", items, more)
+	}
+	items, more = Trim([]int{1, 2}, 2)
+	if len(items) != 2 || more {
+		t.Fatalf("expected 2 items and no more, got %v, %v", items, more)
+	}
+}
+
+func TestSetNextLink(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/timeline?limit=5", nil)
+	w := httptest.NewRecorder()
+	c := Cursor{CreatedAt: time.Now().UTC(), ID: uuid.New()}
+	SetNextLink(w, req, c)
+
+	want := `</api/timeline?cursor=` + c.Encode() + `&limit=5>; rel="next"`
+	if got := w.Header().Get("Link"); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestRankedCursorRoundTrip(t *testing.T) {
+	c := RankedCursor{
+		Rank: 0.0607927,
+		Cursor: Cursor{
+			CreatedAt: time.Date(2026, 3, 14, 15, 9, 26, 535897000, time.UTC),
+			ID:        uuid.New(),
+		},
+	}
+	got, err := DecodeRanked(c.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rank != c.Rank || !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID {
+		t.Fatalf("expected %+v, got %+v", c, got)
+	}
+	if _, err := DecodeRanked(c.Cursor.Encode()); err == nil {
+		t.Error("DecodeRanked accepted an unranked cursor")
+	}
+}
@@ -0,0 +1,151 @@
+// Package search parses chirp search queries. Besides words, a query can
+// hold "quoted phrases", -exclusions, #hashtags and the operators from:,
+// since: and until:.
+package search
+
+import (
+	"chirpy/internal/entities"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const dateLayout = "2006-01-02"
+
+var ErrEmptyQuery = errors.New("search query is empty")
+
+// Query is a parsed search query.
+type Query struct {
+	// Text is the query's words, phrases and exclusions in the syntax of
+	// Postgres's websearch_to_tsquery, or "" if it has none.
+	Text string
+	// From is the handle, without @, the chirps must be by.
+	From string
+	// Since and Until bound the day the chirps were written, in UTC. Since
+	// is inclusive and Until is exclusive; either may be zero.
+	Since time.Time
+	Until time.Time
+	// Hashtags are normalized tags the chirps must all use, and
+	// ExcludedHashtags tags they must not use.
+	Hashtags         []string
+	ExcludedHashtags []string
+}
+
+// Parse parses q. Operators it doesn't know, like foo:bar, are searched
+// for as words. until: includes the whole of the day it names.
+func Parse(q string) (Query, error) {
+	query := Query{Hashtags: []string{}, ExcludedHashtags: []string{}}
+	var text []string
+	for _, token := range tokenize(q) {
+		word, negated := token.text, token.negated
+		if token.quoted {
+			if phrase := strings.Join(strings.Fields(word), " "); phrase != "" {
+				text = append(text, sign(negated)+`"`+phrase+`"`)
+			}
+			continue
+		}
+
+		if op, value, ok := strings.Cut(word, ":"); ok && value != "" {
+			switch strings.ToLower(op) {
+			case "from":
+				if negated {
+					return Query{}, errors.New("from: can't be excluded")
+				}
+				handle := strings.TrimPrefix(value, "@")
+				if !entities.ValidHandle(handle) {
+					return Query{}, fmt.Errorf("from: needs a handle, not %q", value)
+				}
+				query.From = strings.ToLower(handle)
+				continue
+			case "since", "until":
+				if negated {
+					return Query{}, fmt.Errorf("%s: can't be excluded", op)
+				}
+				day, err := time.Parse(dateLayout, value)
+				if err != nil {
+					return Query{}, fmt.Errorf("%s: needs a date like 2026-01-31, not %q", op, value)
+				}
+				if strings.EqualFold(op, "since") {
+					query.Since = day
+				} else {
+					query.Until = day.AddDate(0, 0, 1)
+				}
+				continue
+			}
+		}
+
+		if strings.HasPrefix(word, "#") || strings.HasPrefix(word, "＃") {
+			if tag, ok := entities.NormalizeHashtag(word); ok {
+				// each tag once, since a chirp uses a tag at most once
+				tags := &query.Hashtags
+				if negated {
+					tags = &query.ExcludedHashtags
+				}
+				if !slices.Contains(*tags, tag) {
+					*tags = append(*tags, tag)
+				}
+				continue
+			}
+		}
+
+		// websearch_to_tsquery reads a bare "or" as an operator, and quotes
+		// and dashes are ours to give meaning to
+		word = strings.Trim(word, `"-`)
+		if word == "" || strings.EqualFold(word, "or") {
+			continue
+		}
+		text = append(text, sign(negated)+word)
+	}
+
+	query.Text = strings.Join(text, " ")
+	if query.Text == "" && query.From == "" && query.Since.IsZero() && query.Until.IsZero() &&
+		len(query.Hashtags) == 0 && len(query.ExcludedHashtags) == 0 {
+		return Query{}, ErrEmptyQuery
+	}
+	return query, nil
+}
+
+type token struct {
+	text    string
+	quoted  bool
+	negated bool
+}
+
+// tokenize splits q at spaces, keeping quoted phrases whole. A phrase with
+// no closing quote runs to the end of q.
+func tokenize(q string) []token {
+	var tokens []token
+	for {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if q == "" {
+			return tokens
+		}
+		var t token
+		if len(q) > 1 && q[0] == '-' && !unicode.IsSpace(rune(q[1])) {
+			t.negated = true
+			q = q[1:]
+		}
+		if q[0] == '"' {
+			t.quoted = true
+			phrase, rest, _ := strings.Cut(q[1:], `"`)
+			t.text, q = phrase, rest
+		} else {
+			end := strings.IndexFunc(q, unicode.IsSpace)
+			if end < 0 {
+				end = len(q)
+			}
+			t.text, q = q[:end], q[end:]
+		}
+		tokens = append(tokens, t)
+	}
+}
+
+func sign(negated bool) string {
+	if negated {
+		return "-"
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		q    string
+		want Query
+	}{
+		{"golang generics", Query{Text: "golang generics"}},
+		{`"hello world" -spam`, Query{Text: `"hello world" -spam`}},
+		{`-"bad  take"`, Query{Text: `-"bad take"`}},
+		{`"unclosed phrase`, Query{Text: `"unclosed phrase"`}},
+		{"from:@Alice coffee", Query{Text: "coffee", From: "alice"}},
+		{"#Go -#Rust tips", Query{Text: "tips", Hashtags: []string{"go"}, ExcludedHashtags: []string{"rust"}}},
+		{"since:2026-01-01 until:2026-01-31 launch", Query{
+			Text:  "launch",
+			Since: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			Until: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{"#go #Go -#rust -#RUST", Query{Hashtags: []string{"go"}, ExcludedHashtags: []string{"rust"}}},
+		{"cats or dogs", Query{Text: "cats dogs"}},
+		{"lang:go #1", Query{Text: "lang:go #1"}},
+		{"- lonely dash", Query{Text: "lonely dash"}},
+	}
+	for _, tt := range tests {
+		if tt.want.Hashtags == nil {
+			tt.want.Hashtags = []string{}
+		}
+		if tt.want.ExcludedHashtags == nil {
+			tt.want.ExcludedHashtags = []string{}
+		}
+		got, err := Parse(tt.q)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.q, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, q := range []string{
+		"",
+		"   ",
+		`""`,
+		"from:not-a-handle",
+		"-from:alice",
+		"since:yesterday",
+		"until:2026-13-01",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q) succeeded", q)
+		}
+	}
+}
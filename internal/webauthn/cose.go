@@ -0,0 +1,87 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers (RFC 9053) for the signatures we accept.
+const (
+	AlgorithmES256 = -7
+	AlgorithmEdDSA = -8
+)
+
+// COSE key labels and values
+const (
+	coseKeyType      = 1
+	coseAlgorithm    = 3
+	coseCurve        = -1
+	coseX            = -2
+	coseY            = -3
+	coseKeyTypeOKP   = 1
+	coseKeyTypeEC2   = 2
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+// parseCOSEKey decodes a credential public key at the start of data and
+// returns the bytes after it.
+func parseCOSEKey(data []byte) (int64, crypto.PublicKey, []byte, error) {
+	value, rest, err := decodeCBOR(data)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("malformed credential public key: %s", err)
+	}
+	m, ok := value.(map[any]any)
+	if !ok {
+		return 0, nil, nil, errors.New("credential public key is not a map")
+	}
+	kty, _ := m[int64(coseKeyType)].(int64)
+	alg, _ := m[int64(coseAlgorithm)].(int64)
+	crv, _ := m[int64(coseCurve)].(int64)
+	x, _ := m[int64(coseX)].([]byte)
+
+	switch {
+	case alg == AlgorithmES256 && kty == coseKeyTypeEC2 && crv == coseCurveP256:
+		y, _ := m[int64(coseY)].([]byte)
+		if len(x) != 32 || len(y) != 32 {
+			return 0, nil, nil, errors.New("malformed P-256 public key")
+		}
+		// ecdh checks the point is on the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return 0, nil, nil, fmt.Errorf("invalid P-256 public key: %s", err)
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		return alg, key, rest, nil
+	case alg == AlgorithmEdDSA && kty == coseKeyTypeOKP && crv == coseCurveEd25519:
+		if len(x) != ed25519.PublicKeySize {
+			return 0, nil, nil, errors.New("malformed Ed25519 public key")
+		}
+		return alg, ed25519.PublicKey(x), rest, nil
+	default:
+		return 0, nil, nil, fmt.Errorf("unsupported credential key (kty %d, alg %d, crv %d)", kty, alg, crv)
+	}
+}
+
+func verifySignature(key crypto.PublicKey, data, signature []byte) bool {
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, signature)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,377 @@
+// Package webauthn implements the relying party side of WebAuthn passkey
+// registration and authentication ceremonies. It supports ES256 and EdDSA
+// credentials and "none" attestation, which is all a passkey login needs:
+// we trust the user's device, not its manufacturer.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const ChallengeTimeout = 5 * time.Minute
+
+// authenticator data flags
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+	flagExtensionData    = 0x80
+)
+
+var (
+	ErrChallengeMismatch = errors.New("webauthn: challenge does not match")
+	ErrOriginMismatch    = errors.New("webauthn: origin is not allowed")
+	ErrBadSignature      = errors.New("webauthn: signature is invalid")
+	// the authenticator's counter went backwards, so the credential may
+	// have been cloned
+	ErrSignCount = errors.New("webauthn: sign count did not increase")
+)
+
+// Base64URL is binary data that JSON encodes as unpadded base64url, the way
+// the WebAuthn JSON serialization does.
+type Base64URL []byte
+
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return fmt.Errorf("webauthn: invalid base64url: %s", err)
+	}
+	*b = decoded
+	return nil
+}
+
+// RelyingParty is the site passkeys are registered with. ID is its domain,
+// and Origins are the exact origins, like https://chirpy.example, that
+// ceremonies may come from.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+type User struct {
+	// an opaque handle, never an email address or other personal data
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string    `json:"type"`
+	ID   Base64URL `json:"id"`
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	result := []CredentialDescriptor{}
+	for _, id := range ids {
+		result = append(result, CredentialDescriptor{Type: "public-key", ID: id})
+	}
+	return result
+}
+
+// CreationOptions is the publicKey argument to navigator.credentials.create,
+// in the form PublicKeyCredential.parseCreationOptionsFromJSON takes.
+type CreationOptions struct {
+	RP struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User                   User                   `json:"user"`
+	Challenge              Base64URL              `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey        string `json:"residentKey"`
+		RequireResidentKey bool   `json:"requireResidentKey"`
+		UserVerification   string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	Attestation string `json:"attestation"`
+}
+
+// CreationOptions asks for a discoverable, user-verifying credential, so
+// it can log in on its own without a password. exclude lists the user's
+// existing credential IDs so the same authenticator isn't registered twice.
+func (rp RelyingParty) CreationOptions(challenge []byte, user User, exclude [][]byte) CreationOptions {
+	options := CreationOptions{
+		User:               user,
+		Challenge:          challenge,
+		Timeout:            ChallengeTimeout.Milliseconds(),
+		ExcludeCredentials: descriptors(exclude),
+		Attestation:        "none",
+	}
+	options.RP.ID = rp.ID
+	options.RP.Name = rp.Name
+	for _, alg := range []int{AlgorithmEdDSA, AlgorithmES256} {
+		options.PubKeyCredParams = append(options.PubKeyCredParams, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	options.AuthenticatorSelection.ResidentKey = "required"
+	options.AuthenticatorSelection.RequireResidentKey = true
+	options.AuthenticatorSelection.UserVerification = "required"
+	return options
+}
+
+// RequestOptions is the publicKey argument to navigator.credentials.get, in
+// the form PublicKeyCredential.parseRequestOptionsFromJSON takes.
+type RequestOptions struct {
+	Challenge        Base64URL              `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RequestOptions starts a login. With no allowed credentials the browser
+// offers every passkey it has for the site.
+func (rp RelyingParty) RequestOptions(challenge []byte, allow [][]byte) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          ChallengeTimeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: "required",
+	}
+}
+
+// RegistrationResponse is the JSON form of the PublicKeyCredential that
+// navigator.credentials.create resolves to.
+type RegistrationResponse struct {
+	ID       string    `json:"id"`
+	RawID    Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AttestationObject Base64URL `json:"attestationObject"`
+		Transports        []string  `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON form of the PublicKeyCredential that
+// navigator.credentials.get resolves to.
+type AssertionResponse struct {
+	ID       string    `json:"id"`
+	RawID    Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AuthenticatorData Base64URL `json:"authenticatorData"`
+		Signature         Base64URL `json:"signature"`
+		UserHandle        Base64URL `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential is what the relying party stores about a registered passkey.
+type Credential struct {
+	ID []byte
+	// COSE_Key encoded
+	PublicKey  []byte
+	Algorithm  int64
+	SignCount  uint32
+	Transports []string
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func (rp RelyingParty) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("webauthn: malformed client data: %s", err)
+	}
+	if data.Type != ceremony {
+		return fmt.Errorf("webauthn: client data is for %q, not %q", data.Type, ceremony)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+	if !slices.Contains(rp.Origins, data.Origin) {
+		return ErrOriginMismatch
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	flags     byte
+	signCount uint32
+	// only present during registration
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseAuthenticatorData checks the parts of authenticator data common to
+// both ceremonies: that it is for this relying party and that the user was
+// present and verified.
+func (rp RelyingParty) parseAuthenticatorData(data []byte) (authenticatorData, error) {
+	if len(data) < 37 {
+		return authenticatorData{}, errors.New("webauthn: authenticator data is too short")
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(data[:32], rpIDHash[:]) {
+		return authenticatorData{}, errors.New("webauthn: authenticator data is for another relying party")
+	}
+	parsed := authenticatorData{
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if parsed.flags&flagUserPresent == 0 {
+		return authenticatorData{}, errors.New("webauthn: user was not present")
+	}
+	if parsed.flags&flagUserVerified == 0 {
+		return authenticatorData{}, errors.New("webauthn: user was not verified")
+	}
+
+	rest := data[37:]
+	if parsed.flags&flagAttestedCredData != 0 {
+		// aaguid (16 bytes), credential id length (2), credential id, key
+		if len(rest) < 18 {
+			return authenticatorData{}, errors.New("webauthn: attested credential data is too short")
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return authenticatorData{}, errors.New("webauthn: credential id is truncated")
+		}
+		parsed.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+		_, _, after, err := parseCOSEKey(rest)
+		if err != nil {
+			return authenticatorData{}, fmt.Errorf("webauthn: %s", err)
+		}
+		parsed.publicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+	if parsed.flags&flagExtensionData != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return authenticatorData{}, fmt.Errorf("webauthn: malformed extensions: %s", err)
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return authenticatorData{}, errors.New("webauthn: trailing bytes in authenticator data")
+	}
+	return parsed, nil
+}
+
+// VerifyRegistration checks a registration ceremony against the challenge
+// it was started with and returns the new credential to store.
+func (rp RelyingParty) VerifyRegistration(resp RegistrationResponse, challenge []byte) (Credential, error) {
+	if resp.Type != "public-key" {
+		return Credential{}, errors.New("webauthn: credential is not a public key credential")
+	}
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return Credential{}, err
+	}
+
+	attestation, err := decodeCBORMap(resp.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn: malformed attestation object: %s", err)
+	}
+	if format, _ := attestation["fmt"].(string); format != "none" {
+		return Credential{}, fmt.Errorf("webauthn: unsupported attestation format %q", format)
+	}
+	if statement, _ := attestation["attStmt"].(map[any]any); len(statement) != 0 {
+		return Credential{}, errors.New("webauthn: none attestation has a statement")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return Credential{}, errors.New("webauthn: attestation object has no authenticator data")
+	}
+
+	authData, err := rp.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if authData.credentialID == nil {
+		return Credential{}, errors.New("webauthn: registration has no attested credential")
+	}
+	if !bytes.Equal(authData.credentialID, resp.RawID) {
+		return Credential{}, errors.New("webauthn: credential id does not match")
+	}
+	if len(authData.credentialID) > 1023 {
+		return Credential{}, errors.New("webauthn: credential id is too long")
+	}
+	alg, _, _, err := parseCOSEKey(authData.publicKey)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn: %s", err)
+	}
+
+	return Credential{
+		ID:         authData.credentialID,
+		PublicKey:  authData.publicKey,
+		Algorithm:  alg,
+		SignCount:  authData.signCount,
+		Transports: resp.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion checks a login ceremony against the challenge it was
+// started with and the stored credential, and returns the credential's new
+// sign count to store.
+func (rp RelyingParty) VerifyAssertion(resp AssertionResponse, challenge []byte, credential Credential) (uint32, error) {
+	if resp.Type != "public-key" {
+		return 0, errors.New("webauthn: credential is not a public key credential")
+	}
+	if !bytes.Equal(resp.RawID, credential.ID) {
+		return 0, errors.New("webauthn: assertion is for another credential")
+	}
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	authData, err := rp.parseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	_, key, rest, err := parseCOSEKey(credential.PublicKey)
+	if err != nil || len(rest) != 0 {
+		return 0, errors.New("webauthn: stored public key is invalid")
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(append([]byte{}, resp.Response.AuthenticatorData...), clientDataHash[:]...)
+	if !verifySignature(key, signed, resp.Response.Signature) {
+		return 0, ErrBadSignature
+	}
+
+	// authenticators that don't keep a counter always send 0
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, ErrSignCount
+	}
+	return authData.signCount, nil
+}
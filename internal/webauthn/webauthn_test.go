@@ -0,0 +1,281 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+)
+
+// encodeCBOR is just enough of a CBOR encoder to play the authenticator.
+func encodeCBOR(value any) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 1<<8:
+			return []byte{major<<5 | 24, byte(n)}
+		case n < 1<<16:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		default:
+			return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+		}
+	}
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return head(cborNegative, uint64(-1-v))
+		}
+		return head(cborUnsigned, uint64(v))
+	case []byte:
+		return append(head(cborBytes, uint64(len(v))), v...)
+	case string:
+		return append(head(cborText, uint64(len(v))), v...)
+	case map[any]any:
+		var entries [][]byte
+		for key, value := range v {
+			entries = append(entries, append(encodeCBOR(key), encodeCBOR(value)...))
+		}
+		// canonical order, so keys encode deterministically
+		sort.Slice(entries, func(i, j int) bool { return string(entries[i]) < string(entries[j]) })
+		out := head(cborMap, uint64(len(v)))
+		for _, entry := range entries {
+			out = append(out, entry...)
+		}
+		return out
+	default:
+		panic("encodeCBOR: unsupported type")
+	}
+}
+
+// authenticator is a software passkey holding a single credential.
+type authenticator struct {
+	credentialID []byte
+	signer       crypto.Signer
+	alg          int
+	signCount    uint32
+}
+
+func newAuthenticator(t *testing.T, alg int) *authenticator {
+	t.Helper()
+	a := &authenticator{credentialID: make([]byte, 16), alg: alg, signCount: 1}
+	rand.Read(a.credentialID)
+	var err error
+	switch alg {
+	case AlgorithmES256:
+		a.signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, a.signer, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func (a *authenticator) coseKey() []byte {
+	switch key := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		x, y := make([]byte, 32), make([]byte, 32)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+		return encodeCBOR(map[any]any{
+			coseKeyType: coseKeyTypeEC2, coseAlgorithm: AlgorithmES256,
+			coseCurve: coseCurveP256, coseX: x, coseY: y,
+		})
+	case ed25519.PublicKey:
+		return encodeCBOR(map[any]any{
+			coseKeyType: coseKeyTypeOKP, coseAlgorithm: AlgorithmEdDSA,
+			coseCurve: coseCurveEd25519, coseX: []byte(key),
+		})
+	}
+	panic("unreachable")
+}
+
+func (a *authenticator) authData(rpID string, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	flags := byte(flagUserPresent | flagUserVerified)
+	if attested {
+		flags |= flagAttestedCredData
+	}
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+func clientDataJSON(ceremony string, challenge []byte, origin string) []byte {
+	data, _ := json.Marshal(clientData{
+		Type:      ceremony,
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Origin:    origin,
+	})
+	return data
+}
+
+func (a *authenticator) create(rpID, origin string, challenge []byte) RegistrationResponse {
+	var resp RegistrationResponse
+	resp.ID = base64.RawURLEncoding.EncodeToString(a.credentialID)
+	resp.RawID = a.credentialID
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = clientDataJSON("webauthn.create", challenge, origin)
+	resp.Response.AttestationObject = encodeCBOR(map[any]any{
+		"fmt":      "none",
+		"attStmt":  map[any]any{},
+		"authData": a.authData(rpID, true),
+	})
+	return resp
+}
+
+func (a *authenticator) get(t *testing.T, rpID, origin string, challenge []byte) AssertionResponse {
+	t.Helper()
+	a.signCount++
+	var resp AssertionResponse
+	resp.ID = base64.RawURLEncoding.EncodeToString(a.credentialID)
+	resp.RawID = a.credentialID
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = clientDataJSON("webauthn.get", challenge, origin)
+	resp.Response.AuthenticatorData = a.authData(rpID, false)
+
+	hash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(append([]byte{}, resp.Response.AuthenticatorData...), hash[:]...)
+	var err error
+	if a.alg == AlgorithmES256 {
+		digest := sha256.Sum256(signed)
+		resp.Response.Signature, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	} else {
+		resp.Response.Signature, err = a.signer.Sign(rand.Reader, signed, crypto.Hash(0))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+var testRP = RelyingParty{ID: "chirpy.example", Name: "Chirpy", Origins: []string{"https://chirpy.example"}}
+
+func register(t *testing.T, a *authenticator) Credential {
+	t.Helper()
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential, err := testRP.VerifyRegistration(a.create(testRP.ID, testRP.Origins[0], challenge), challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credential
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	for _, alg := range []int{AlgorithmES256, AlgorithmEdDSA} {
+		a := newAuthenticator(t, alg)
+		credential := register(t, a)
+		if credential.Algorithm != int64(alg) {
+			t.Fatalf("expected algorithm %d, got %d", alg, credential.Algorithm)
+		}
+
+		for range 2 {
+			challenge, _ := NewChallenge()
+			count, err := testRP.VerifyAssertion(a.get(t, testRP.ID, testRP.Origins[0], challenge), challenge, credential)
+			if err != nil {
+				t.Fatalf("alg %d: %v", alg, err)
+			}
+			if count != a.signCount {
+				t.Fatalf("expected sign count %d, got %d", a.signCount, count)
+			}
+			credential.SignCount = count
+		}
+	}
+}
+
+func TestRegistrationRejectsWrongChallengeAndOrigin(t *testing.T) {
+	a := newAuthenticator(t, AlgorithmES256)
+	challenge, _ := NewChallenge()
+	other, _ := NewChallenge()
+
+	_, err := testRP.VerifyRegistration(a.create(testRP.ID, testRP.Origins[0], other), challenge)
+	if !errors.Is(err, ErrChallengeMismatch) {
+		t.Fatalf("expected ErrChallengeMismatch, got %v", err)
+	}
+	_, err = testRP.VerifyRegistration(a.create(testRP.ID, "https://evil.example", challenge), challenge)
+	if !errors.Is(err, ErrOriginMismatch) {
+		t.Fatalf("expected ErrOriginMismatch, got %v", err)
+	}
+	if _, err = testRP.VerifyRegistration(a.create("evil.example", testRP.Origins[0], challenge), challenge); err == nil {
+		t.Fatal("expected an error for another relying party's authenticator data")
+	}
+}
+
+func TestAssertionRejectsTamperingAndClones(t *testing.T) {
+	a := newAuthenticator(t, AlgorithmEdDSA)
+	credential := register(t, a)
+	challenge, _ := NewChallenge()
+
+	resp := a.get(t, testRP.ID, testRP.Origins[0], challenge)
+	resp.Response.Signature[0] ^= 0xff
+	if _, err := testRP.VerifyAssertion(resp, challenge, credential); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("expected ErrBadSignature, got %v", err)
+	}
+
+	credential.SignCount = a.signCount + 10
+	resp = a.get(t, testRP.ID, testRP.Origins[0], challenge)
+	if _, err := testRP.VerifyAssertion(resp, challenge, credential); !errors.Is(err, ErrSignCount) {
+		t.Fatalf("expected ErrSignCount, got %v", err)
+	}
+
+	// a registration response can't be replayed as a login
+	create := a.create(testRP.ID, testRP.Origins[0], challenge)
+	resp.Response.ClientDataJSON = create.Response.ClientDataJSON
+	if _, err := testRP.VerifyAssertion(resp, challenge, credential); err == nil {
+		t.Fatal("expected an error for webauthn.create client data")
+	}
+}
+
+func TestBase64URLAcceptsPadding(t *testing.T) {
+	var b Base64URL
+	if err := json.Unmarshal([]byte(`"_-8="`), &b); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "\xff\xef" {
+		t.Fatalf("got %x", []byte(b))
+	}
+	out, _ := json.Marshal(b)
+	if string(out) != `"_-8"` {
+		t.Fatalf("got %s", out)
+	}
+}
+
+func TestDecodeCBORRejectsMalformedInput(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"truncated":       {0x43, 0x01},
+		"indefinite":      {0x9f, 0xff},
+		"duplicate key":   {0xa2, 0x01, 0x01, 0x01, 0x02},
+		"huge array":      {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"float":           {0xf9, 0x3c, 0x00},
+		"trailing":        {0xa0, 0x00},
+		"deeply nested":   {0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81},
+		"array map key":   {0xa1, 0x80, 0x01},
+		"not a map":       {0x01},
+		"int64 overflow":  {0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"empty":           {},
+		"truncated count": {0x19, 0x01},
+	} {
+		if _, err := decodeCBORMap(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// keyDirPollInterval is how often the key directory is re-read for keys
+// rotated by other instances. It is separate from the keyring's own limit on
+// reloads triggered by an unknown kid.
+const keyDirPollInterval = 1 * time.Minute
+
+// newSigner signs tokens with the asymmetric keys in JWT_KEY_DIR when it is
+// set, and falls back to HS256 with JWT_SECRET otherwise. The keyring is nil
+// in the fallback case.
+func newSigner(algorithm string) (auth.Signer, *auth.Keyring) {
+	dir := os.Getenv("JWT_KEY_DIR")
+	if dir == "" {
+		return auth.NewHMACSigner(os.Getenv("JWT_SECRET")), nil
+	}
+
+	retention := 24 * time.Hour
+	if s := os.Getenv("JWT_KEY_RETENTION"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			log.Fatal("Invalid JWT_KEY_RETENTION:", err)
+		}
+		retention = d
+	}
+	// a retired key must keep verifying until everything it signed has
+	// expired, and email verification tokens live longest
+	if retention < emailVerificationExpiry {
+		log.Fatalf("JWT_KEY_RETENTION must be at least %s, the longest a signed token lasts", emailVerificationExpiry)
+	}
+
+	keyring, err := auth.LoadKeyring(dir, retention)
+	if err != nil {
+		log.Fatal("Failed to load signing keys:", err)
+	}
+	if _, err := keyring.Active(); err != nil {
+		// an empty key directory gets its first key on startup
+		if _, err := keyring.Rotate(algorithm); err != nil {
+			log.Fatal("Failed to create signing key:", err)
+		}
+	}
+
+	// other instances sharing the directory may rotate keys
+	go func() {
+		for range time.Tick(keyDirPollInterval) {
+			if err := keyring.Reload(); err != nil {
+				log.Printf("failed to reload signing keys: %s", err)
+			}
+		}
+	}()
+
+	return keyring, keyring
+}
+
+func (cfg *apiConfig) handlerJWKS(w http.ResponseWriter, req *http.Request) {
+	set := auth.JWKSet{Keys: []auth.JWK{}}
+	if cfg.keyring != nil {
+		set = cfg.keyring.JWKS()
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, set, http.StatusOK)
+}
+
+func (cfg *apiConfig) handlerRotateKeys(w http.ResponseWriter, req *http.Request) {
+	if cfg.keyring == nil {
+		respondWithError(w, "key rotation requires JWT_KEY_DIR", http.StatusConflict)
+		return
+	}
+
+	type parameters struct {
+		Algorithm string `json:"algorithm"`
+	}
+
+	type response struct {
+		ID        string `json:"kid"`
+		Algorithm string `json:"alg"`
+	}
+
+	params := parameters{Algorithm: cfg.keyAlgorithm}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, "malformed rotation request", http.StatusBadRequest)
+		return
+	}
+	if params.Algorithm != auth.AlgorithmEdDSA && params.Algorithm != auth.AlgorithmRS256 {
+		respondWithError(w, "algorithm must be EdDSA or RS256", http.StatusBadRequest)
+		return
+	}
+
+	key, err := cfg.keyring.Rotate(params.Algorithm)
+	if err != nil {
+		respondWithError(w, "failed to rotate signing key", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, response{ID: key.ID, Algorithm: key.Algorithm}, http.StatusCreated)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LikedChirp struct {
+	Chirp
+	LikedAt time.Time `json:"liked_at"`
+}
+
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	// liking a rechirp likes the chirp it shares, so likes aren't split
+	// across copies
+	original, err := cfg.resolveOriginal(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	// liking a chirp twice is not an error, and only counts once
+	_, err = cfg.db.LikeChirp(req.Context(), database.LikeChirpParams{
+		UserID:  p.UserID,
+		ChirpID: original,
+	})
+	if isForeignKeyViolation(err) {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "Could not like chirp", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	original, err := cfg.resolveOriginal(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	_, err = cfg.db.UnlikeChirp(req.Context(), database.UnlikeChirpParams{
+		UserID:  p.UserID,
+		ChirpID: original,
+	})
+	if err != nil {
+		respondWithError(w, "Could not unlike chirp", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerListUserLikes lists the chirps a user has liked, most recently
+// liked first, a page at a time.
+func (cfg *apiConfig) handlerListUserLikes(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("user_id"))
+	if err != nil {
+		respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	page, err := pagination.ParseParams(req.URL.Query(), defaultPageSize, maxPageSize)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := cfg.db.GetUser(req.Context(), userID); err != nil {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	beforeCreatedAt, beforeID := cursorArgs(page.After)
+	rows, err := cfg.db.ListUserLikes(req.Context(), database.ListUserLikesParams{
+		UserID:          userID,
+		BeforeCreatedAt: beforeCreatedAt,
+		BeforeID:        beforeID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, "Could not list likes", http.StatusInternalServerError)
+		return
+	}
+
+	rows, more := pagination.Trim(rows, page.Limit)
+	chirps := []database.Chirp{}
+	for _, row := range rows {
+		chirps = append(chirps, database.Chirp{
+			ID:         row.ID,
+			CreatedAt:  row.CreatedAt,
+			UpdatedAt:  row.UpdatedAt,
+			Body:       row.Body,
+			UserID:     row.UserID,
+			EditedAt:   row.EditedAt,
+			InReplyTo:  row.InReplyTo,
+			ReplyCount: row.ReplyCount,
+			LikeCount:  row.LikeCount,
+			Kind:       row.Kind,
+			RechirpOf:  row.RechirpOf,
+			QuoteOf:    row.QuoteOf,
+		})
+	}
+	rendered, err := cfg.renderChirps(req, chirps)
+	if err != nil {
+		respondWithError(w, "Could not list likes", http.StatusInternalServerError)
+		return
+	}
+
+	result := []LikedChirp{}
+	for i, row := range rows {
+		result = append(result, LikedChirp{Chirp: rendered[i], LikedAt: row.LikedAt})
+	}
+	if more {
+		last := rows[len(rows)-1]
+		pagination.SetNextLink(w, req, pagination.Cursor{CreatedAt: last.LikedAt, ID: last.ID})
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
+
+// likedByMe returns which of chirpIDs the user making req has liked.
+// Requests that aren't signed in, or whose token can't read chirps, haven't
+// liked anything.
+func (cfg *apiConfig) likedByMe(req *http.Request, chirpIDs []uuid.UUID) map[uuid.UUID]bool {
+	liked := map[uuid.UUID]bool{}
+	if len(chirpIDs) == 0 || req.Header.Get("Authorization") == "" {
+		return liked
+	}
+	p, err := cfg.authenticate(req)
+	if err != nil || !p.can(auth.ScopeChirpsRead) {
+		return liked
+	}
+
+	ids, err := cfg.db.ListLikedChirpIDs(req.Context(), database.ListLikedChirpIDsParams{
+		UserID:   p.UserID,
+		ChirpIds: chirpIDs,
+	})
+	if err != nil {
+		// the chirps are still worth showing without the flag
+		log.Printf("failed to look up likes for user %s: %s", p.UserID, err)
+		return liked
+	}
+	for _, id := range ids {
+		liked[id] = true
+	}
+	return liked
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// accountLockoutPolicy is the default account policy with the lockout
+// threshold and duration overridable from the environment.
+func accountLockoutPolicy() auth.LockoutPolicy {
+	policy := auth.DefaultAccountLockoutPolicy
+	if s := os.Getenv("LOGIN_LOCKOUT_THRESHOLD"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			log.Fatal("Invalid LOGIN_LOCKOUT_THRESHOLD:", err)
+		}
+		policy.LockoutThreshold = n
+	}
+	if s := os.Getenv("LOGIN_LOCKOUT_DURATION"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			log.Fatal("Invalid LOGIN_LOCKOUT_DURATION:", err)
+		}
+		policy.LockoutDuration = d
+	}
+	return policy
+}
+
+func accountLoginKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipLoginKey(req *http.Request) string {
+	return "ip:" + clientIP(req)
+}
+
+// checkLoginLockout responds and returns false when either the account or
+// the client's address is locked out. Account locks get 423 Locked, address
+// locks 429 Too Many Requests; both say when to retry.
+func (cfg *apiConfig) checkLoginLockout(w http.ResponseWriter, req *http.Request, email string) bool {
+	until, status, locked := cfg.loginLock(req, email)
+	if !locked {
+		return true
+	}
+	retryAfter := int(math.Ceil(time.Until(until).Seconds()))
+	w.Header().Set("Retry-After", fmt.Sprint(retryAfter))
+	respondWithError(w, "too many failed login attempts, try again later", status)
+	return false
+}
+
+// loginLock reports whether the client's address or the account is locked
+// out, until when, and the status to answer with.
+func (cfg *apiConfig) loginLock(req *http.Request, email string) (time.Time, int, bool) {
+	now := time.Now()
+	checks := []struct {
+		key    string
+		status int
+	}{
+		{ipLoginKey(req), http.StatusTooManyRequests},
+		{accountLoginKey(email), http.StatusLocked},
+	}
+	for _, check := range checks {
+		attempt, err := cfg.db.GetLoginAttempt(req.Context(), check.key)
+		if err != nil || !attempt.LockedUntil.Valid || !now.Before(attempt.LockedUntil.Time) {
+			continue
+		}
+		return attempt.LockedUntil.Time, check.status, true
+	}
+	return time.Time{}, 0, false
+}
+
+// recordLoginFailure counts a failed attempt against both the account and
+// the client's address. It is recorded for unknown emails too, so lockouts
+// don't reveal which accounts exist.
+func (cfg *apiConfig) recordLoginFailure(ctx context.Context, req *http.Request, email string) {
+	cfg.recordFailure(ctx, accountLoginKey(email), cfg.accountLockout)
+	cfg.recordFailure(ctx, ipLoginKey(req), cfg.ipLockout)
+}
+
+func (cfg *apiConfig) recordFailure(ctx context.Context, key string, policy auth.LockoutPolicy) {
+	now := time.Now()
+	failures, err := cfg.db.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Key:         key,
+		WindowStart: now.Add(-policy.Window),
+	})
+	if err != nil {
+		log.Printf("failed to record login failure for %s: %s", key, err)
+		return
+	}
+	delay := policy.Delay(int(failures))
+	if delay == 0 {
+		return
+	}
+	err = cfg.db.LockLogin(ctx, database.LockLoginParams{
+		Key:         key,
+		LockedUntil: sql.NullTime{Time: now.Add(delay), Valid: true},
+	})
+	if err != nil {
+		log.Printf("failed to lock login for %s: %s", key, err)
+	}
+}
+
+// clearLoginFailures unlocks an account. The address counter is left alone so
+// an attacker can't reset it by logging in to an account of their own.
+func (cfg *apiConfig) clearLoginFailures(ctx context.Context, email string) error {
+	return cfg.db.ClearLoginAttempts(ctx, accountLoginKey(email))
+}
+
+func (cfg *apiConfig) handlerUnlockAccount(w http.ResponseWriter, req *http.Request) {
+	if err := cfg.clearLoginFailures(req.Context(), req.PathValue("email")); err != nil {
+		respondWithError(w, "Could not unlock account", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
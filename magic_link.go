@@ -0,0 +1,124 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/mailer"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const magicLinkExpiry = 15 * time.Minute
+
+// appURL is where the web app lives, for links in emails. APP_URL
+// overrides the default.
+func appURL() string {
+	url := os.Getenv("APP_URL")
+	if url == "" {
+		url = "http://localhost:" + port + "/app"
+	}
+	return strings.TrimSuffix(url, "/")
+}
+
+func (cfg *apiConfig) handlerRequestMagicLink(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed login form", http.StatusBadRequest)
+		return
+	}
+
+	// like a password reset, the response never says whether the account exists
+	go cfg.sendMagicLink(context.WithoutCancel(req.Context()), params.Email)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (cfg *apiConfig) sendMagicLink(ctx context.Context, email string) {
+	user, err := cfg.db.GetUserByEmail(ctx, email)
+	if err != nil {
+		return
+	}
+
+	token, _ := auth.MakeRefreshToken()
+	created, err := cfg.db.CreateMagicLinkToken(ctx, database.CreateMagicLinkTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(magicLinkExpiry),
+	})
+	if err != nil {
+		log.Printf("failed to store magic link for user %s: %s", user.ID, err)
+		return
+	}
+	// at most one link a minute, so the endpoint can't be used to flood an inbox
+	if created == 0 {
+		return
+	}
+
+	// the token goes in the fragment so it never reaches server logs
+	link := appURL() + "/login/magic#token=" + token
+	err = cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy sign-in link",
+		Body: "Use the link below to sign in to Chirpy.\n" +
+			"It expires in 15 minutes and can only be used once. If you didn't ask for it, you can ignore this email.\n\n" +
+			link + "\n",
+	})
+	if err != nil {
+		log.Printf("failed to send magic link to user %s: %s", user.ID, err)
+	}
+}
+
+func (cfg *apiConfig) handlerConsumeMagicLink(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Token            string `json:"token"`
+		ExpiresInSeconds int    `json:"expires_in_seconds"`
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed login form", http.StatusBadRequest)
+		return
+	}
+
+	link, err := cfg.db.ConsumeMagicLinkToken(req.Context(), auth.HashToken(params.Token))
+	if err != nil {
+		respondWithError(w, "invalid or expired sign-in link", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := cfg.db.GetUser(req.Context(), link.UserID)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	// a link sent to an address the account has since given up must not
+	// let whoever still reads that mailbox in
+	if link.Email != user.Email {
+		respondWithError(w, "invalid or expired sign-in link", http.StatusUnauthorized)
+		return
+	}
+	// following the link proves the user controls the address
+	verified, err := cfg.db.VerifyUserEmail(req.Context(), database.VerifyUserEmailParams{
+		ID:    user.ID,
+		Email: link.Email,
+	})
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	user.EmailVerifiedAt = verified.EmailVerifiedAt
+
+	cfg.completeLogin(w, req, user, cfg.accessTokenExpiry(params.ExpiresInSeconds))
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"chirpy/internal/mailer"
+	"os"
+)
+
+// newMailer picks a mailer from the environment. Without MAILER=smtp,
+// messages are written to MAIL_DIR (default "mail") instead of being sent.
+func newMailer() mailer.Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "chirpy@localhost"
+	}
+	if os.Getenv("MAILER") == "smtp" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &mailer.SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	}
+	dir := os.Getenv("MAIL_DIR")
+	if dir == "" {
+		dir = "mail"
+	}
+	return &mailer.FileMailer{Dir: dir, From: from}
+}
@@ -3,14 +3,18 @@ package main
 import (
 	"chirpy/internal/auth"
 	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"chirpy/internal/mailer"
+	"chirpy/internal/pagination"
+	"chirpy/internal/webauthn"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"regexp"
-	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -23,15 +27,31 @@ import (
 const (
 	port            = "8080"
 	staticFilesRoot = "."
+	maxChirpLength  = 140
 )
 
 type apiConfig struct {
 	fileServerHits atomic.Int32
 	db             *database.Queries
 	platform       string
-	secret         string
+	signer         auth.Signer
+	keyring        *auth.Keyring
+	keyAlgorithm   string
 	jwtExpiry      time.Duration
 	paymentAPIKey  string
+	adminAPIKey    string
+	accountLockout auth.LockoutPolicy
+	ipLockout      auth.LockoutPolicy
+	mailer         mailer.Mailer
+	// when set, users must verify their email before logging in or chirping
+	requireEmailVerification bool
+	accountDeletionGrace     time.Duration
+	passwordPolicy           auth.PasswordPolicy
+	passwordParams           auth.PasswordParams
+	relyingParty             webauthn.RelyingParty
+	// how long after posting a chirp can be edited; zero means forever
+	chirpEditWindow    time.Duration
+	redChirpEditWindow time.Duration
 }
 
 type User struct {
@@ -40,15 +60,70 @@ type User struct {
 	UpdatedAt   sql.NullTime `json:"updated_at"`
 	Email       string       `json:"email"`
 	IsChirpyRed bool         `json:"is_chirpy_red"`
+	Handle      *string      `json:"handle"`
 }
 
 type Chirp struct {
-	ID        uuid.UUID    `json:"id"`
-	CreatedAt sql.NullTime `json:"created_at"`
-	UpdatedAt sql.NullTime `json:"updated_at"`
-	UserID    uuid.UUID    `json:"user_id"`
-	Body      string       `json:"body"`
-	Valid     bool         `json:"valid"`
+	ID         uuid.UUID    `json:"id"`
+	CreatedAt  sql.NullTime `json:"created_at"`
+	UpdatedAt  sql.NullTime `json:"updated_at"`
+	UserID     uuid.UUID    `json:"user_id"`
+	Body       string       `json:"body"`
+	Valid      bool         `json:"valid"`
+	Edited     bool         `json:"edited"`
+	EditedAt   *time.Time   `json:"edited_at"`
+	InReplyTo  *uuid.UUID   `json:"in_reply_to"`
+	ReplyCount int32        `json:"reply_count"`
+	LikeCount  int32        `json:"like_count"`
+	// whether the user making the request has liked the chirp
+	LikedByMe bool `json:"liked_by_me"`
+	// "chirp", "rechirp" or "quote"
+	Kind string `json:"kind"`
+	// the chirp a rechirp or quote points at, null for other chirps and
+	// for quotes whose original was deleted
+	OriginalID *uuid.UUID `json:"original_id"`
+	Original   *Chirp     `json:"original,omitempty"`
+	// hashtags, mentions and URLs in the body
+	Entities []ChirpEntity `json:"entities"`
+}
+
+func chirpFromDB(chirp database.Chirp) Chirp {
+	return Chirp{
+		ID:         chirp.ID,
+		CreatedAt:  chirp.CreatedAt,
+		UpdatedAt:  chirp.UpdatedAt,
+		UserID:     chirp.UserID,
+		Body:       chirp.Body,
+		Valid:      true,
+		Edited:     chirp.EditedAt.Valid,
+		EditedAt:   timeOrNil(chirp.EditedAt),
+		InReplyTo:  uuidOrNil(chirp.InReplyTo),
+		ReplyCount: chirp.ReplyCount,
+		LikeCount:  chirp.LikeCount,
+		Kind:       chirp.Kind,
+		OriginalID: originalID(chirp),
+	}
+}
+
+func originalID(chirp database.Chirp) *uuid.UUID {
+	if chirp.RechirpOf.Valid {
+		return &chirp.RechirpOf.UUID
+	}
+	return uuidOrNil(chirp.QuoteOf)
+}
+
+func uuidOrNil(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func stringOrNil(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
 }
 
 func main() {
@@ -62,36 +137,131 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 	dbQueries := database.New(db)
+	if len(os.Args) > 1 {
+		if err := runCommand(dbQueries, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	mux := http.NewServeMux()
+	keyAlgorithm := os.Getenv("JWT_KEY_ALGORITHM")
+	if keyAlgorithm == "" {
+		keyAlgorithm = auth.AlgorithmEdDSA
+	}
+	signer, keyring := newSigner(keyAlgorithm)
 	cfg := apiConfig{
 		fileServerHits: atomic.Int32{},
 		db:             dbQueries,
 		platform:       os.Getenv("PLATFORM"),
-		secret:         os.Getenv("JWT_SECRET"),
+		signer:         signer,
+		keyring:        keyring,
+		keyAlgorithm:   keyAlgorithm,
 		jwtExpiry:      1 * time.Hour,
 		paymentAPIKey:  os.Getenv("POLKA_KEY"),
-	}
+		adminAPIKey:    os.Getenv("ADMIN_API_KEY"),
+		accountLockout: accountLockoutPolicy(),
+		ipLockout:      auth.DefaultIPLockoutPolicy,
+		mailer:         newMailer(),
+
+		requireEmailVerification: os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true",
+		accountDeletionGrace:     accountDeletionGracePeriod(),
+		passwordPolicy:           passwordPolicy(),
+		passwordParams:           passwordHashParams(),
+		relyingParty:             relyingParty(),
+		chirpEditWindow:          editWindowFromEnv("CHIRP_EDIT_WINDOW", 15*time.Minute),
+		redChirpEditWindow:       editWindowFromEnv("CHIRP_EDIT_WINDOW_RED", 1*time.Hour),
+	}
+	cfg.startAccountDeletionWorker()
+	cfg.startTrendingWorker()
 
 	fileServerHandler := http.FileServer(http.Dir(staticFilesRoot))
 	mux.Handle("/app/", http.StripPrefix("/app", cfg.middlewareMetricsInc(fileServerHandler)))
 
-	mux.HandleFunc("GET /admin/metrics", cfg.handlerMetrics)
-	mux.HandleFunc("POST /admin/reset", cfg.handlerReset)
+	admin := func(handler http.HandlerFunc) http.Handler {
+		return cfg.middlewareRequireRole(auth.RoleAdmin, handler)
+	}
+	mux.Handle("GET /admin/metrics", admin(cfg.handlerMetrics))
+	mux.Handle("POST /admin/reset", admin(cfg.handlerReset))
+	mux.Handle("POST /admin/keys/rotate", admin(cfg.handlerRotateKeys))
+	mux.Handle("DELETE /admin/lockouts/{email}", admin(cfg.handlerUnlockAccount))
+	mux.Handle("PUT /admin/users/{user_id}/role", admin(cfg.handlerSetUserRole))
+
+	mux.Handle("DELETE /moderation/chirps/{chirp_id}",
+		cfg.middlewareRequireRole(auth.RoleModerator, http.HandlerFunc(cfg.handlerModerateDeleteChirp)))
+
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.handlerJWKS)
 
 	mux.HandleFunc("GET /api/healthz", checkReadiness)
 	mux.HandleFunc("POST /api/users", cfg.handlerCreateUser)
 	mux.HandleFunc("PUT /api/users", cfg.handlerUpdateUser)
+	mux.HandleFunc("DELETE /api/users", cfg.handlerDeleteUser)
+	mux.HandleFunc("POST /api/users/verify", cfg.handlerVerifyEmail)
+	mux.HandleFunc("POST /api/users/{user_id}/follow", cfg.handlerFollowUser)
+	mux.HandleFunc("DELETE /api/users/{user_id}/follow", cfg.handlerUnfollowUser)
+	mux.HandleFunc("GET /api/users/{user_id}/followers", cfg.handlerListFollowers)
+	mux.HandleFunc("GET /api/users/{user_id}/following", cfg.handlerListFollowing)
+	mux.HandleFunc("GET /api/users/{user_id}/likes", cfg.handlerListUserLikes)
 	mux.HandleFunc("POST /api/login", cfg.handlerLogin)
+	mux.HandleFunc("POST /api/login/mfa", cfg.handlerLoginMFA)
+	mux.HandleFunc("POST /api/login/magic", cfg.handlerRequestMagicLink)
+	mux.HandleFunc("POST /api/login/magic/consume", cfg.handlerConsumeMagicLink)
+	mux.HandleFunc("POST /api/login/passkey/begin", cfg.handlerBeginPasskeyLogin)
+	mux.HandleFunc("POST /api/login/passkey/finish", cfg.handlerFinishPasskeyLogin)
 	mux.HandleFunc("POST /api/refresh", cfg.handlerRefresh)
 	mux.HandleFunc("POST /api/revoke", cfg.handlerRevoke)
-
+	mux.HandleFunc("GET /api/sessions", cfg.handlerListSessions)
+	mux.HandleFunc("DELETE /api/sessions/{session_id}", cfg.handlerRevokeSession)
+	mux.HandleFunc("POST /api/sessions/revoke-all", cfg.handlerRevokeAllSessions)
+	mux.HandleFunc("POST /api/tokens", cfg.handlerCreateAPIToken)
+	mux.HandleFunc("GET /api/tokens", cfg.handlerListAPITokens)
+	mux.HandleFunc("DELETE /api/tokens/{token_id}", cfg.handlerRevokeAPIToken)
+	mux.HandleFunc("POST /api/passkeys/register/begin", cfg.handlerBeginPasskeyRegistration)
+	mux.HandleFunc("POST /api/passkeys/register/finish", cfg.handlerFinishPasskeyRegistration)
+	mux.HandleFunc("GET /api/passkeys", cfg.handlerListPasskeys)
+	mux.HandleFunc("DELETE /api/passkeys/{passkey_id}", cfg.handlerDeletePasskey)
+	mux.HandleFunc("POST /api/oauth/clients", cfg.handlerCreateOAuthClient)
+	mux.HandleFunc("POST /api/password/forgot", cfg.handlerForgotPassword)
+	mux.HandleFunc("POST /api/password/reset", cfg.handlerResetPassword)
+
+	mux.HandleFunc("POST /api/2fa/totp", cfg.handlerEnrollTOTP)
+	mux.HandleFunc("POST /api/2fa/totp/confirm", cfg.handlerConfirmTOTP)
+	mux.HandleFunc("DELETE /api/2fa/totp", cfg.handlerDisableTOTP)
+
+	mux.HandleFunc("GET /api/timeline", cfg.handlerGetTimeline)
 	mux.HandleFunc("GET /api/chirps", cfg.handlerGetChirps)
 	mux.HandleFunc("GET /api/chirps/{chirp_id}", cfg.handlerGetChirpByID)
 	mux.HandleFunc("POST /api/chirps", cfg.handlerCreateChirp)
+	mux.HandleFunc("PUT /api/chirps/{chirp_id}", cfg.handlerEditChirp)
 	mux.HandleFunc("DELETE /api/chirps/{chirp_id}", cfg.handlerDeleteChirp)
+	mux.HandleFunc("GET /api/chirps/{chirp_id}/revisions", cfg.handlerGetChirpRevisions)
+	mux.HandleFunc("GET /api/chirps/{chirp_id}/thread", cfg.handlerGetThread)
+	mux.HandleFunc("POST /api/chirps/{chirp_id}/like", cfg.handlerLikeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirp_id}/like", cfg.handlerUnlikeChirp)
+	mux.HandleFunc("POST /api/chirps/{chirp_id}/rechirp", cfg.handlerRechirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirp_id}/rechirp", cfg.handlerUndoRechirp)
+	mux.HandleFunc("POST /api/chirps/{chirp_id}/bookmark", cfg.handlerBookmarkChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirp_id}/bookmark", cfg.handlerDeleteBookmark)
+
+	mux.HandleFunc("GET /api/bookmarks", cfg.handlerListBookmarks)
+	mux.HandleFunc("GET /api/bookmarks/folders", cfg.handlerListBookmarkFolders)
+	mux.HandleFunc("POST /api/bookmarks/folders", cfg.handlerCreateBookmarkFolder)
+	mux.HandleFunc("DELETE /api/bookmarks/folders/{folder_id}", cfg.handlerDeleteBookmarkFolder)
+
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", cfg.handlerGetHashtagChirps)
+	mux.HandleFunc("GET /api/trending", cfg.handlerGetTrending)
+	mux.HandleFunc("GET /api/search/chirps", cfg.handlerSearchChirps)
+
+	mux.HandleFunc("GET /api/notifications", cfg.handlerListNotifications)
+	mux.HandleFunc("POST /api/notifications/read", cfg.handlerMarkNotificationsRead)
 
 	mux.HandleFunc("POST /api/polka/webhooks", cfg.handlePayment)
 
+	mux.HandleFunc("GET /oauth/authorize", cfg.handlerAuthorize)
+	mux.HandleFunc("POST /oauth/authorize", cfg.handlerAuthorizeConsent)
+	mux.HandleFunc("POST /oauth/token", cfg.handlerOAuthToken)
+	mux.HandleFunc("POST /oauth/revoke", cfg.handlerOAuthRevoke)
+	mux.HandleFunc("POST /oauth/introspect", cfg.handlerOAuthIntrospect)
+
 	server := &http.Server{
 		Addr:    ":" + port,
 		Handler: mux,
@@ -143,6 +313,7 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, req *http.Request
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 
 	type result struct {
@@ -156,25 +327,45 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, req *http.Request
 		respondWithError(w, "Something went wrong", http.StatusBadRequest)
 		return
 	}
-	hashedPassword, err := auth.HashPassword(params.Password)
+	if params.Handle != "" && !entities.ValidHandle(params.Handle) {
+		respondWithError(w, invalidHandleMessage, http.StatusBadRequest)
+		return
+	}
+	if !cfg.checkPasswordPolicy(w, params.Password, params.Email) {
+		return
+	}
+	hashedPassword, err := auth.HashPasswordWithParams(params.Password, cfg.passwordParams)
 	if err != nil {
 		respondWithError(w, "invalid password (or password could not be hashed)", http.StatusInternalServerError)
 		return
 	}
 
-	createUserParams := database.CreateUserParams{Email: params.Email, HashedPassword: hashedPassword}
+	createUserParams := database.CreateUserParams{
+		Email:          params.Email,
+		HashedPassword: hashedPassword,
+		Handle:         nullString(params.Handle),
+	}
 	user, err := cfg.db.CreateUser(req.Context(), createUserParams)
+	if violatesConstraint(err, handleConstraint) {
+		respondWithError(w, "handle is already taken", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		respondWithError(w, "Something went wrong", http.StatusBadRequest)
 		return
 	}
 
+	if err := cfg.sendVerificationEmail(req.Context(), user.ID, user.Email); err != nil {
+		log.Printf("failed to send verification email to user %s: %s", user.ID, err)
+	}
+
 	userCreated := User{
 		ID:          user.ID,
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 		Email:       user.Email,
 		IsChirpyRed: user.IsChirpyRed.Bool,
+		Handle:      stringOrNil(user.Handle),
 	}
 
 	respondWithJSON(w, userCreated, http.StatusCreated)
@@ -182,32 +373,68 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, req *http.Request
 }
 
 func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, req *http.Request) {
-	token := auth.GetBearerToken(req.Header)
-	userID, err := auth.ValidateJWT(token, cfg.secret)
-	if err != nil {
-		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+	p, ok := cfg.authorize(w, req, auth.ScopeProfileWrite)
+	if !ok {
 		return
 	}
+	userID := p.UserID
 
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		// needed to change the email or password
+		CurrentPassword string `json:"current_password"`
+		// "" removes the handle
+		Handle *string `json:"handle"`
 	}
 
 	params := parameters{}
 	decoder := json.NewDecoder(req.Body)
-	err = decoder.Decode(&params)
+	err := decoder.Decode(&params)
 	if err != nil {
 		respondWithError(w, "invalid resonse body", http.StatusBadRequest)
 		return
 	}
+	if params.Handle != nil && *params.Handle != "" && !entities.ValidHandle(*params.Handle) {
+		respondWithError(w, invalidHandleMessage, http.StatusBadRequest)
+		return
+	}
 
 	if params.Email != "" && !validateEmail(params.Email) {
 		respondWithError(w, "invalid email", http.StatusBadRequest)
 		return
 	}
-	if params.Password != "" && !validatePassword(params.Password) {
-		respondWithError(w, "invalid password", http.StatusBadRequest)
+	// the email and password are how the account is recovered and signed
+	// into, so changing them takes the user's own session and their
+	// current password, never just a delegated token
+	var current database.User
+	if params.Email != "" || params.Password != "" {
+		if p.Scopes != nil {
+			respondWithError(w, "changing your email or password needs a signed-in session", http.StatusForbidden)
+			return
+		}
+		current, err = cfg.db.GetUser(req.Context(), userID)
+		if err != nil {
+			respondWithError(w, "failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if !cfg.checkLoginLockout(w, req, current.Email) {
+			return
+		}
+		valid, err := auth.CheckPasswordHash(params.CurrentPassword, current.HashedPassword)
+		if err != nil {
+			respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			cfg.recordLoginFailure(req.Context(), req, current.Email)
+			respondWithError(w, "current password is incorrect", http.StatusUnauthorized)
+			return
+		}
+	}
+	// the password shouldn't be built from the email it is changed along
+	// with, or from the one the account already has
+	if params.Password != "" && !cfg.checkPasswordPolicy(w, params.Password, current.Email, params.Email) {
 		return
 	}
 
@@ -222,10 +449,13 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, req *http.Request
 			return
 		}
 		userOut = user
+		if err := cfg.sendVerificationEmail(req.Context(), user.ID, user.Email); err != nil {
+			log.Printf("failed to send verification email to user %s: %s", user.ID, err)
+		}
 	}
 
 	if params.Password != "" {
-		hashedPassword, err := auth.HashPassword(params.Password)
+		hashedPassword, err := auth.HashPasswordWithParams(params.Password, cfg.passwordParams)
 		if err != nil {
 			respondWithError(w, "failed to hash password", http.StatusInternalServerError)
 			return
@@ -241,12 +471,29 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, req *http.Request
 
 	}
 
+	if params.Handle != nil {
+		user, err := cfg.db.UpdateUserHandle(req.Context(), database.UpdateUserHandleParams{
+			ID:     userID,
+			Handle: nullString(*params.Handle),
+		})
+		if violatesConstraint(err, handleConstraint) {
+			respondWithError(w, "handle is already taken", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			respondWithError(w, "failed to update handle", http.StatusInternalServerError)
+			return
+		}
+		userOut = database.UpdateUserEmailRow(user)
+	}
+
 	respondWithJSON(w, User{
 		ID:          userOut.ID,
 		CreatedAt:   userOut.CreatedAt,
 		UpdatedAt:   userOut.UpdatedAt,
 		Email:       userOut.Email,
 		IsChirpyRed: userOut.IsChirpyRed.Bool,
+		Handle:      stringOrNil(userOut.Handle),
 	}, http.StatusOK)
 }
 
@@ -257,20 +504,18 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 		ExpiresInSeconds int    `json:"expires_in_seconds"`
 	}
 
-	type response struct {
-		User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
-	}
-
 	decoder := json.NewDecoder(req.Body)
 	params := parameters{}
 	if err := decoder.Decode(&params); err != nil {
 		respondWithError(w, "malformed login form", http.StatusBadRequest)
 		return
 	}
+	if !cfg.checkLoginLockout(w, req, params.Email) {
+		return
+	}
 	user, err := cfg.db.GetUserByEmail(req.Context(), params.Email)
 	if err != nil {
+		cfg.recordLoginFailure(req.Context(), req, params.Email)
 		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
@@ -283,27 +528,97 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if !valid_password {
+		cfg.recordLoginFailure(req.Context(), req, params.Email)
 		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
-	expiry := 1 * time.Hour
-	if params.ExpiresInSeconds != 0 {
-		expiry = time.Duration(params.ExpiresInSeconds) * time.Second
+	cfg.upgradePasswordHash(req.Context(), user, params.Password)
+
+	if cfg.requireEmailVerification && !user.EmailVerifiedAt.Valid {
+		respondWithError(w, "email address has not been verified", http.StatusForbidden)
+		return
+	}
+	cfg.completeLogin(w, req, user, cfg.accessTokenExpiry(params.ExpiresInSeconds))
+}
+
+// accessTokenExpiry is how long an access token asked to last seconds gets.
+// It is never longer than cfg.jwtExpiry, because retired signing keys are
+// only kept for JWT_KEY_RETENTION and must outlive what they signed.
+func (cfg *apiConfig) accessTokenExpiry(seconds int) time.Duration {
+	expiry := time.Duration(seconds) * time.Second
+	if seconds <= 0 || expiry > cfg.jwtExpiry {
+		return cfg.jwtExpiry
+	}
+	return expiry
+}
+
+// completeLogin finishes a login once the user's first factor has been
+// checked. Users with two-factor authentication enabled get a short-lived
+// mfa token to exchange at POST /api/login/mfa instead of a session.
+func (cfg *apiConfig) completeLogin(w http.ResponseWriter, req *http.Request, user database.User, expiry time.Duration) {
+	type response struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}
+
+	credential, err := cfg.db.GetTOTPCredential(req.Context(), user.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if err == nil && credential.EnabledAt.Valid {
+		mfaToken, err := auth.MakeActionToken(cfg.signer, user.ID, uuid.New(), auth.ActionMFA, mfaTokenExpiry)
+		if err != nil {
+			respondWithError(w, "failed to create token", http.StatusInternalServerError)
+			return
+		}
+		respondWithJSON(w, response{MFARequired: true, MFAToken: mfaToken}, http.StatusOK)
+		return
+	}
+
+	cfg.issueSession(w, req, user, expiry)
+}
+
+// issueSession responds with a new access token and refresh token for user.
+func (cfg *apiConfig) issueSession(w http.ResponseWriter, req *http.Request, user database.User, expiry time.Duration) {
+	type response struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
-	token, err := auth.MakeJWT(user.ID, cfg.secret, expiry)
+
+	token, err := auth.MakeJWTWithSigner(cfg.signer, user.ID, user.Role, expiry)
 
 	if err != nil {
 		respondWithError(w, "failed to create token", http.StatusInternalServerError)
 		return
 	}
 
+	// failures only reset once a login fully succeeds, so a stolen password
+	// can't be used to keep resetting the count while guessing 2FA codes
+	if err := cfg.clearLoginFailures(req.Context(), user.Email); err != nil {
+		log.Printf("failed to clear login failures for user %s: %s", user.ID, err)
+	}
+	cfg.cancelAccountDeletion(req.Context(), user)
+
+	sessionID, err := cfg.db.CreateSession(req.Context(), database.CreateSessionParams{
+		UserID:    user.ID,
+		UserAgent: nullString(req.UserAgent()),
+		IpAddress: nullString(clientIP(req)),
+	})
+	if err != nil {
+		respondWithError(w, "failed to create session", http.StatusInternalServerError)
+		return
+	}
+
 	refreshToken, _ := auth.MakeRefreshToken()
-	refreshTokenParams := database.CreateRefreshTokenParams{
-		Token:  refreshToken,
-		UserID: user.ID,
+	refreshTokenParams := database.CreateRefreshTokenInFamilyParams{
+		Token:    refreshToken,
+		UserID:   user.ID,
+		FamilyID: sessionID,
 	}
 
-	rt, err := cfg.db.CreateRefreshToken(req.Context(), refreshTokenParams)
+	rt, err := cfg.db.CreateRefreshTokenInFamily(req.Context(), refreshTokenParams)
 	if err != nil {
 		respondWithError(w, "invalid refresh token", http.StatusBadRequest)
 		return
@@ -316,6 +631,7 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 			UpdatedAt:   user.UpdatedAt,
 			Email:       user.Email,
 			IsChirpyRed: user.IsChirpyRed.Bool,
+			Handle:      stringOrNil(user.Handle),
 		},
 		Token:        token,
 		RefreshToken: rt,
@@ -325,8 +641,10 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, req *http.Request) {
 
 	type parameters struct {
-		Body   string    `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
+		Body      string     `json:"body"`
+		UserID    uuid.UUID  `json:"user_id"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
+		QuoteOf   *uuid.UUID `json:"quote_of"`
 	}
 
 	type response = Chirp
@@ -337,108 +655,139 @@ func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, req *http.Reques
 		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
 		return
 	}
-	token := auth.GetBearerToken(req.Header)
-	userID, err := auth.ValidateJWT(token, cfg.secret)
-
-	if err != nil {
-		respondWithError(w, "Bad token", http.StatusUnauthorized)
+	p, ok := cfg.authorize(w, req, auth.ScopeChirpsWrite)
+	if !ok {
 		return
 	}
 
-	user, err := cfg.db.GetUser(req.Context(), userID)
+	user, err := cfg.db.GetUser(req.Context(), p.UserID)
 	if err != nil {
 		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
 		return
 	}
 
+	if cfg.requireEmailVerification && !user.EmailVerifiedAt.Valid {
+		respondWithError(w, "email address has not been verified", http.StatusForbidden)
+		return
+	}
+
 	// chirps may not be longer than 140 chars
-	if len(params.Body) > 140 {
+	if len(params.Body) > maxChirpLength {
 		respondWithJSON(w, response{Valid: false}, http.StatusBadRequest)
 		return
 	}
 
+	inReplyTo := uuid.NullUUID{}
+	if params.InReplyTo != nil {
+		parent, err := cfg.db.GetChirp(req.Context(), *params.InReplyTo)
+		if err != nil {
+			respondWithError(w, "chirp being replied to does not exist", http.StatusBadRequest)
+			return
+		}
+		inReplyTo = uuid.NullUUID{UUID: originalOf(parent), Valid: true}
+	}
+	kind, quoteOf := chirpKindChirp, uuid.NullUUID{}
+	if params.QuoteOf != nil {
+		quoted, err := cfg.db.GetChirp(req.Context(), *params.QuoteOf)
+		if err != nil {
+			respondWithError(w, "chirp being quoted does not exist", http.StatusBadRequest)
+			return
+		}
+		kind, quoteOf = chirpKindQuote, uuid.NullUUID{UUID: originalOf(quoted), Valid: true}
+	}
+
 	// filter taboo words
 	cleanedBody := censor(params.Body)
 
 	// add the chirp to the db
 	chirpParams := database.CreateChirpParams{
-		Body:   cleanedBody,
-		UserID: user.ID,
+		Body:      cleanedBody,
+		UserID:    user.ID,
+		InReplyTo: inReplyTo,
+		Kind:      kind,
+		QuoteOf:   quoteOf,
+		Hashtags:  entities.Hashtags(cleanedBody),
+		Mentions:  entities.Mentions(cleanedBody),
 	}
 	chirp, err := cfg.db.CreateChirp(req.Context(), chirpParams)
+	if isForeignKeyViolation(err) {
+		// the parent or quoted chirp was deleted after we looked it up
+		respondWithError(w, "chirp being replied to or quoted does not exist", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		respondWithError(w, "Could not create chirp", http.StatusInternalServerError)
 		return
 	}
 
-	respondWithJSON(w, response{
-		Valid:     true,
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	}, http.StatusCreated)
+	cfg.respondWithChirp(w, req, database.Chirp(chirp), http.StatusCreated)
 }
 
+// handlerGetChirps lists chirps, optionally by one author, a page at a
+// time. They come oldest first, or newest first with sort=desc.
 func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, req *http.Request) {
-	authorID := req.URL.Query().Get("author_id")
-	type response = []Chirp
-
-	var chirps []database.Chirp
-	var err error
-
-	parsedAuthorID := uuid.Nil
-	if authorID != "" {
-		parsedAuthorID, err = uuid.Parse(authorID)
-		if err != nil {
-			respondWithError(w, "malformed author ID", http.StatusInternalServerError)
-			return
-		}
-	}
-
-	if parsedAuthorID != uuid.Nil {
-		chirps, err = cfg.db.GetChirpsByAuthor(req.Context(), parsedAuthorID)
+	query := req.URL.Query()
+	var authorID uuid.NullUUID
+	if s := query.Get("author_id"); s != "" {
+		id, err := uuid.Parse(s)
 		if err != nil {
-			respondWithError(w, "Could not get all chirps by given author", http.StatusInternalServerError)
-			return
-		}
-	} else {
-		chirps, err = cfg.db.GetAllChirps(req.Context())
-		if err != nil {
-			respondWithError(w, "Could not get all chirps", http.StatusInternalServerError)
+			respondWithError(w, "malformed author ID", http.StatusBadRequest)
 			return
 		}
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	newestFirst := false
+	switch query.Get("sort") {
+	case "", "asc":
+	case "desc":
+		newestFirst = true
+	default:
+		respondWithError(w, "sort must be asc or desc", http.StatusBadRequest)
+		return
 	}
-
-	result := response{}
-	for _, chirp := range chirps {
-		result = append(result, Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		})
+	page, err := pagination.ParseParams(query, defaultPageSize, maxPageSize)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if req.URL.Query().Get("sort") == "asc" {
-		sort.Slice(result, func(i, j int) bool {
-			return result[i].CreatedAt.Time.Before(result[j].CreatedAt.Time)
+	cursorCreatedAt, cursorID := cursorArgs(page.After)
+	var chirps []database.Chirp
+	if newestFirst {
+		chirps, err = cfg.db.ListChirpsNewestFirst(req.Context(), database.ListChirpsNewestFirstParams{
+			AuthorID:        authorID,
+			BeforeCreatedAt: cursorCreatedAt,
+			BeforeID:        cursorID,
+			PageSize:        int32(page.Limit + 1),
 		})
-	} else if req.URL.Query().Get("sort") == "desc" {
-		sort.Slice(result, func(i, j int) bool {
-			return result[i].CreatedAt.Time.After(result[j].CreatedAt.Time)
+	} else {
+		chirps, err = cfg.db.ListChirps(req.Context(), database.ListChirpsParams{
+			AuthorID:       authorID,
+			AfterCreatedAt: cursorCreatedAt,
+			AfterID:        cursorID,
+			PageSize:       int32(page.Limit + 1),
 		})
 	}
+	if err != nil {
+		respondWithError(w, "Could not get chirps", http.StatusInternalServerError)
+		return
+	}
 
+	chirps, more := pagination.Trim(chirps, page.Limit)
+	result, err := cfg.renderChirps(req, chirps)
+	if err != nil {
+		respondWithError(w, "Could not get chirps", http.StatusInternalServerError)
+		return
+	}
+	if more {
+		last := chirps[len(chirps)-1]
+		pagination.SetNextLink(w, req, pagination.Cursor{CreatedAt: last.CreatedAt.Time, ID: last.ID})
+	}
 	respondWithJSON(w, result, http.StatusOK)
 }
 
 func (cfg *apiConfig) handlerGetChirpByID(w http.ResponseWriter, req *http.Request) {
 
-	type response = Chirp
-
 	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
 	if err != nil {
 		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
@@ -451,23 +800,15 @@ func (cfg *apiConfig) handlerGetChirpByID(w http.ResponseWriter, req *http.Reque
 		return
 	}
 
-	respondWithJSON(w, response{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-		Valid:     true,
-	}, http.StatusOK)
+	cfg.respondWithChirp(w, req, chirp, http.StatusOK)
 }
 
 func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Request) {
-	token := auth.GetBearerToken(req.Header)
-	userID, err := auth.ValidateJWT(token, cfg.secret)
-	if err != nil {
-		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+	p, ok := cfg.authorize(w, req, auth.ScopeChirpsWrite)
+	if !ok {
 		return
 	}
+	userID := p.UserID
 	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
 	if err != nil {
 		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
@@ -499,20 +840,66 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Reques
 
 func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, req *http.Request) {
 	refreshToken := auth.GetBearerToken(req.Header)
-	validation, err := cfg.db.ValidateRefreshToken(req.Context(), refreshToken)
-	if err != nil || validation.Valid.Bool == false {
+	stored, err := cfg.db.GetRefreshToken(req.Context(), refreshToken)
+	if err != nil {
 		respondWithError(w, "bad credentials", http.StatusUnauthorized)
 		return
 	}
+
+	// a revoked token being presented again means it was stolen (or replayed),
+	// so every token descended from the same login is no longer trusted
+	if stored.RevokedAt.Valid {
+		cfg.revokeSession(req.Context(), stored.UserID, stored.FamilyID)
+		respondWithError(w, "bad credentials", http.StatusUnauthorized)
+		return
+	}
+	if !stored.ExpiresAt.Valid || time.Now().After(stored.ExpiresAt.Time) {
+		respondWithError(w, "bad credentials", http.StatusUnauthorized)
+		return
+	}
+
+	// revoking only succeeds once, so two concurrent refreshes with the same
+	// token are treated the same as a replay
+	_, err = cfg.db.RotateRefreshToken(req.Context(), stored.Token)
+	if err != nil {
+		cfg.revokeSession(req.Context(), stored.UserID, stored.FamilyID)
+		respondWithError(w, "bad credentials", http.StatusUnauthorized)
+		return
+	}
+
+	newRefreshToken, _ := auth.MakeRefreshToken()
+	rt, err := cfg.db.CreateRefreshTokenInFamily(req.Context(), database.CreateRefreshTokenInFamilyParams{
+		Token:       newRefreshToken,
+		UserID:      stored.UserID,
+		FamilyID:    stored.FamilyID,
+		ParentToken: sql.NullString{String: stored.Token, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	cfg.db.TouchSession(req.Context(), database.TouchSessionParams{
+		ID:        stored.FamilyID,
+		UserAgent: nullString(req.UserAgent()),
+		IpAddress: nullString(clientIP(req)),
+	})
+
 	type response struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	user, err := cfg.db.GetUser(req.Context(), stored.UserID)
+	if err != nil {
+		respondWithError(w, "bad credentials", http.StatusUnauthorized)
+		return
 	}
-	accessToken, err := auth.MakeJWT(validation.UserID, cfg.secret, cfg.jwtExpiry)
+	accessToken, err := auth.MakeJWTWithSigner(cfg.signer, user.ID, user.Role, cfg.jwtExpiry)
 	if err != nil {
 		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
 		return
 	}
-	respondWithJSON(w, response{Token: accessToken}, http.StatusOK)
+	respondWithJSON(w, response{Token: accessToken, RefreshToken: rt}, http.StatusOK)
 }
 
 func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, req *http.Request) {
@@ -521,11 +908,17 @@ func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, req *http.Request) {
 		respondWithError(w, "no token in header", http.StatusBadRequest)
 		return
 	}
-	err := cfg.db.RevokeRefreshToken(req.Context(), refreshToken)
+	stored, err := cfg.db.GetRefreshToken(req.Context(), refreshToken)
 	if err != nil {
 		respondWithError(w, "invalid refresh token", http.StatusBadRequest)
 		return
 	}
+	// revoking a refresh token logs its whole session out
+	err = cfg.revokeSession(req.Context(), stored.UserID, stored.FamilyID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "invalid refresh token", http.StatusBadRequest)
+		return
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -549,10 +942,3 @@ func capitalize(s string) string {
 func validateEmail(email string) bool {
 	return regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`).MatchString(email)
 }
-
-func validatePassword(password string) bool {
-	if password == "" {
-		return false
-	}
-	return true
-}
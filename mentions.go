@@ -0,0 +1,76 @@
+package main
+
+import (
+	"chirpy/internal/entities"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// the unique index on lower(handle)
+	handleConstraint     = "users_handle_idx"
+	invalidHandleMessage = "handles are 1 to 15 letters, digits or underscores"
+)
+
+// ChirpEntity is a hashtag, mention or URL in a chirp's body, so clients can
+// link it without parsing the body themselves. Start and End are byte
+// offsets into the body and RuneStart and RuneEnd count runes.
+type ChirpEntity struct {
+	Type string `json:"type"`
+	// the tag without #, the handle without @, both lower-cased, or the URL
+	Text      string `json:"text"`
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
+	RuneStart int    `json:"rune_start"`
+	RuneEnd   int    `json:"rune_end"`
+	// the user a mention refers to
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+}
+
+// chirpEntities returns the entities in body. mentioned maps the handles
+// the chirp mentioned, as resolved when it was written, to their users;
+// mentions of anyone else are left as plain text.
+func chirpEntities(body string, mentioned map[string]uuid.UUID) []ChirpEntity {
+	result := []ChirpEntity{}
+	for _, entity := range entities.Parse(body) {
+		var userID *uuid.UUID
+		if entity.Type == entities.TypeMention {
+			id, ok := mentioned[entity.Text]
+			if !ok {
+				continue
+			}
+			userID = &id
+		}
+		result = append(result, ChirpEntity{
+			Type:      entity.Type,
+			Text:      entity.Text,
+			Start:     entity.Start,
+			End:       entity.End,
+			RuneStart: entity.RuneStart,
+			RuneEnd:   entity.RuneEnd,
+			UserID:    userID,
+		})
+	}
+	return result
+}
+
+// chirpMentions returns, for each of chirpIDs, the users it mentions by
+// handle.
+func (cfg *apiConfig) chirpMentions(ctx context.Context, chirpIDs []uuid.UUID) (map[uuid.UUID]map[string]uuid.UUID, error) {
+	mentions := map[uuid.UUID]map[string]uuid.UUID{}
+	if len(chirpIDs) == 0 {
+		return mentions, nil
+	}
+	rows, err := cfg.db.ListChirpMentions(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if mentions[row.ChirpID] == nil {
+			mentions[row.ChirpID] = map[string]uuid.UUID{}
+		}
+		mentions[row.ChirpID][row.Handle] = row.UserID
+	}
+	return mentions, nil
+}
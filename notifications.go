@@ -0,0 +1,83 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification tells a user that someone else did something involving
+// them. For now that is mentioning them in a chirp.
+type Notification struct {
+	ID   uuid.UUID `json:"id"`
+	Type string    `json:"type"`
+	// who did it, and in which chirp
+	ActorID   uuid.UUID  `json:"actor_id"`
+	ChirpID   uuid.UUID  `json:"chirp_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+// handlerListNotifications lists the caller's notifications, newest first,
+// a page at a time.
+func (cfg *apiConfig) handlerListNotifications(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeNotificationsRead)
+	if !ok {
+		return
+	}
+	page, err := pagination.ParseParams(req.URL.Query(), defaultPageSize, maxPageSize)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	beforeCreatedAt, beforeID := cursorArgs(page.After)
+	notifications, err := cfg.db.ListNotifications(req.Context(), database.ListNotificationsParams{
+		UserID:          p.UserID,
+		BeforeCreatedAt: beforeCreatedAt,
+		BeforeID:        beforeID,
+		PageSize:        int32(page.Limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, "Could not list notifications", http.StatusInternalServerError)
+		return
+	}
+
+	notifications, more := pagination.Trim(notifications, page.Limit)
+	result := []Notification{}
+	for _, notification := range notifications {
+		result = append(result, Notification{
+			ID:        notification.ID,
+			Type:      notification.Kind,
+			ActorID:   notification.ActorID,
+			ChirpID:   notification.ChirpID,
+			CreatedAt: notification.CreatedAt,
+			ReadAt:    timeOrNil(notification.ReadAt),
+		})
+	}
+	if more {
+		last := notifications[len(notifications)-1]
+		pagination.SetNextLink(w, req, pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
+
+// handlerMarkNotificationsRead marks all of the caller's notifications as
+// read.
+func (cfg *apiConfig) handlerMarkNotificationsRead(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeNotificationsRead)
+	if !ok {
+		return
+	}
+
+	if _, err := cfg.db.MarkNotificationsRead(req.Context(), p.UserID); err != nil {
+		respondWithError(w, "Could not mark notifications read", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	authorizationCodeExpiry = 5 * time.Minute
+	oauthAccessTokenExpiry  = 1 * time.Hour
+)
+
+var scopeDescriptions = map[string]string{
+	auth.ScopeChirpsRead:        "Read chirps",
+	auth.ScopeChirpsWrite:       "Post and delete chirps as you",
+	auth.ScopeProfileWrite:      "Change your handle",
+	auth.ScopeFollowsWrite:      "Follow and unfollow people as you",
+	auth.ScopeBookmarksRead:     "See your private bookmarks and bookmark folders",
+	auth.ScopeBookmarksWrite:    "Add and remove your bookmarks and bookmark folders",
+	auth.ScopeNotificationsRead: "See your notifications and mark them read",
+}
+
+type OAuthClient struct {
+	ID           string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func oauthClientFromDB(client database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Confidential: client.SecretHash.Valid,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// OAuthError is the error body the token, revocation and introspection
+// endpoints use, as RFC 6749 section 5.2 defines it.
+type OAuthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func respondWithOAuthError(w http.ResponseWriter, code, description string, status int) {
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, OAuthError{Error: code, Description: description}, status)
+}
+
+func (cfg *apiConfig) handlerCreateOAuthClient(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+
+	type response struct {
+		OAuthClient
+		ClientSecret string `json:"client_secret,omitempty"`
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed client registration", http.StatusBadRequest)
+		return
+	}
+	if params.Name == "" {
+		respondWithError(w, "client name is required", http.StatusBadRequest)
+		return
+	}
+	if len(params.RedirectURIs) == 0 {
+		respondWithError(w, "at least one redirect uri is required", http.StatusBadRequest)
+		return
+	}
+	for _, uri := range params.RedirectURIs {
+		if !validRedirectURI(uri) {
+			respondWithError(w, "invalid redirect uri "+uri, http.StatusBadRequest)
+			return
+		}
+	}
+
+	secret := ""
+	secretHash := sql.NullString{}
+	if params.Confidential {
+		secret, _ = auth.MakeRefreshToken()
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	client, err := cfg.db.CreateOAuthClient(req.Context(), database.CreateOAuthClientParams{
+		ID:           uuid.NewString(),
+		OwnerID:      userID,
+		Name:         params.Name,
+		SecretHash:   secretHash,
+		RedirectUris: params.RedirectURIs,
+	})
+	if err != nil {
+		respondWithError(w, "Could not register client", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, response{OAuthClient: oauthClientFromDB(client), ClientSecret: secret}, http.StatusCreated)
+}
+
+// validRedirectURI accepts https URLs, http URLs on the loopback interface
+// for native apps, and private-use schemes like com.example.app:/callback.
+// Fragments are never allowed.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Fragment != "" || u.Scheme == "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		ip := net.ParseIP(u.Hostname())
+		return u.Hostname() == "localhost" || ip != nil && ip.IsLoopback()
+	default:
+		return strings.Contains(u.Scheme, ".")
+	}
+}
+
+// authorizeRequest holds the parameters of an authorization request. The
+// consent form posts them back as hidden fields.
+type authorizeRequest struct {
+	ClientID      string
+	RedirectURI   string
+	Scope         string
+	State         string
+	CodeChallenge string
+
+	client database.OauthClient
+	scopes []string
+}
+
+// parseAuthorizeRequest validates an authorization request. If the client
+// or redirect uri is bad it responds with an error page and returns false:
+// redirecting to an unverified uri would make us an open redirector. Any
+// other problem is reported to the client by redirecting back to it.
+func (cfg *apiConfig) parseAuthorizeRequest(w http.ResponseWriter, req *http.Request) (authorizeRequest, bool) {
+	form := req.Form
+	ar := authorizeRequest{
+		ClientID:      form.Get("client_id"),
+		RedirectURI:   form.Get("redirect_uri"),
+		Scope:         form.Get("scope"),
+		State:         form.Get("state"),
+		CodeChallenge: form.Get("code_challenge"),
+	}
+
+	client, err := cfg.db.GetOAuthClient(req.Context(), ar.ClientID)
+	if err != nil {
+		http.Error(w, "Unknown client", http.StatusBadRequest)
+		return ar, false
+	}
+	if ar.RedirectURI == "" && len(client.RedirectUris) == 1 {
+		ar.RedirectURI = client.RedirectUris[0]
+	}
+	if !slices.Contains(client.RedirectUris, ar.RedirectURI) {
+		http.Error(w, "redirect_uri is not registered for this client", http.StatusBadRequest)
+		return ar, false
+	}
+	ar.client = client
+
+	if form.Get("response_type") != "code" {
+		ar.redirectWithError(w, req, "unsupported_response_type", "only the code response type is supported")
+		return ar, false
+	}
+	if ar.CodeChallenge == "" || form.Get("code_challenge_method") != "S256" {
+		ar.redirectWithError(w, req, "invalid_request", "a code_challenge using the S256 method is required")
+		return ar, false
+	}
+	ar.scopes = strings.Fields(ar.Scope)
+	if len(ar.scopes) == 0 {
+		ar.redirectWithError(w, req, "invalid_scope", "at least one scope is required")
+		return ar, false
+	}
+	for _, scope := range ar.scopes {
+		if !auth.ValidScope(scope) {
+			ar.redirectWithError(w, req, "invalid_scope", "unknown scope "+scope)
+			return ar, false
+		}
+	}
+	return ar, true
+}
+
+func (ar authorizeRequest) redirect(w http.ResponseWriter, req *http.Request, params url.Values) {
+	u, _ := url.Parse(ar.RedirectURI)
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if ar.State != "" {
+		query.Set("state", ar.State)
+	}
+	u.RawQuery = query.Encode()
+	http.Redirect(w, req, u.String(), http.StatusFound)
+}
+
+func (ar authorizeRequest) redirectWithError(w http.ResponseWriter, req *http.Request, code, description string) {
+	ar.redirect(w, req, url.Values{"error": {code}, "error_description": {description}})
+}
+
+var consentPage = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Authorize {{.Client}}</title>
+</head>
+<body>
+  <h1>{{.Client}} wants to use your Chirpy account</h1>
+  <p>It will be able to:</p>
+  <ul>
+    {{range .Scopes}}<li>{{.}}</li>
+    {{end}}
+  </ul>
+  {{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+  <form method="post" action="/oauth/authorize">
+    <input type="hidden" name="response_type" value="code">
+    <input type="hidden" name="code_challenge_method" value="S256">
+    <input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+    <input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+    <input type="hidden" name="scope" value="{{.Request.Scope}}">
+    <input type="hidden" name="state" value="{{.Request.State}}">
+    <input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+    <label>Email <input type="email" name="email" value="{{.Email}}" autocomplete="username"></label>
+    <label>Password <input type="password" name="password" autocomplete="current-password"></label>
+    <label>Authentication code (if you use two-factor authentication)
+      <input type="text" name="code" inputmode="numeric" autocomplete="one-time-code">
+    </label>
+    <button type="submit" name="decision" value="approve">Allow</button>
+    <button type="submit" name="decision" value="deny">Deny</button>
+  </form>
+</body>
+</html>
+`))
+
+func (cfg *apiConfig) renderConsentPage(w http.ResponseWriter, ar authorizeRequest, email, message string, status int) {
+	scopes := []string{}
+	for _, scope := range ar.scopes {
+		scopes = append(scopes, scopeDescriptions[scope])
+	}
+	// the page takes a password, so it must never be framed by the client
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	err := consentPage.Execute(w, struct {
+		Client  string
+		Scopes  []string
+		Request authorizeRequest
+		Email   string
+		Error   string
+	}{ar.client.Name, scopes, ar, email, message})
+	if err != nil {
+		log.Printf("failed to render consent page: %s", err)
+	}
+}
+
+func (cfg *apiConfig) handlerAuthorize(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Malformed authorization request", http.StatusBadRequest)
+		return
+	}
+	ar, ok := cfg.parseAuthorizeRequest(w, req)
+	if !ok {
+		return
+	}
+	cfg.renderConsentPage(w, ar, "", "", http.StatusOK)
+}
+
+// handlerAuthorizeConsent handles the consent form. The user signs in on the
+// form itself, so the client never sees their password and a forged
+// submission can't grant anything without it.
+func (cfg *apiConfig) handlerAuthorizeConsent(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Malformed authorization request", http.StatusBadRequest)
+		return
+	}
+	ar, ok := cfg.parseAuthorizeRequest(w, req)
+	if !ok {
+		return
+	}
+	if req.PostForm.Get("decision") != "approve" {
+		ar.redirectWithError(w, req, "access_denied", "the user denied the request")
+		return
+	}
+
+	email := req.PostForm.Get("email")
+	if _, status, locked := cfg.loginLock(req, email); locked {
+		cfg.renderConsentPage(w, ar, email, "Too many failed sign-in attempts. Try again later.", status)
+		return
+	}
+
+	user, err := cfg.db.GetUserByEmail(req.Context(), email)
+	if err != nil {
+		cfg.recordLoginFailure(req.Context(), req, email)
+		cfg.renderConsentPage(w, ar, email, "Incorrect email or password.", http.StatusUnauthorized)
+		return
+	}
+	valid, err := auth.CheckPasswordHash(req.PostForm.Get("password"), user.HashedPassword)
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		cfg.recordLoginFailure(req.Context(), req, email)
+		cfg.renderConsentPage(w, ar, email, "Incorrect email or password.", http.StatusUnauthorized)
+		return
+	}
+	cfg.upgradePasswordHash(req.Context(), user, req.PostForm.Get("password"))
+	if cfg.requireEmailVerification && !user.EmailVerifiedAt.Valid {
+		cfg.renderConsentPage(w, ar, email, "Verify your email address before signing in.", http.StatusForbidden)
+		return
+	}
+
+	code := req.PostForm.Get("code")
+	ok, err = cfg.checkSecondFactor(req.Context(), user.ID, secondFactor{Code: code})
+	if err == nil && !ok {
+		message := "Enter the code from your authenticator app."
+		if code != "" {
+			cfg.recordLoginFailure(req.Context(), req, email)
+			message = "Incorrect authentication code."
+		}
+		cfg.renderConsentPage(w, ar, email, message, http.StatusUnauthorized)
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, errTOTPNotEnabled) {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if err := cfg.clearLoginFailures(req.Context(), user.Email); err != nil {
+		log.Printf("failed to clear login failures for %s: %s", user.Email, err)
+	}
+
+	raw, _ := auth.MakeRefreshToken()
+	err = cfg.db.CreateOAuthAuthorizationCode(req.Context(), database.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(raw),
+		ClientID:      ar.client.ID,
+		UserID:        user.ID,
+		RedirectUri:   ar.RedirectURI,
+		Scopes:        ar.scopes,
+		CodeChallenge: ar.CodeChallenge,
+		ExpiresAt:     time.Now().Add(authorizationCodeExpiry),
+	})
+	if err != nil {
+		ar.redirectWithError(w, req, "server_error", "could not issue an authorization code")
+		return
+	}
+
+	ar.redirect(w, req, url.Values{"code": {raw}})
+}
+
+// authenticateOAuthClient identifies the client calling the token,
+// revocation or introspection endpoint, from HTTP Basic credentials or
+// client_id and client_secret form fields. Public clients only send their
+// id. When it returns false it has already responded.
+func (cfg *apiConfig) authenticateOAuthClient(w http.ResponseWriter, req *http.Request) (database.OauthClient, bool) {
+	clientID, secret, basic := req.BasicAuth()
+	if !basic {
+		clientID = req.PostForm.Get("client_id")
+		secret = req.PostForm.Get("client_secret")
+	}
+
+	client, err := cfg.db.GetOAuthClient(req.Context(), clientID)
+	if err == nil && client.SecretHash.Valid {
+		given := auth.HashToken(secret)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(client.SecretHash.String)) != 1 {
+			err = errors.New("wrong client secret")
+		}
+	}
+	if err != nil {
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		}
+		respondWithOAuthError(w, "invalid_client", "client authentication failed", http.StatusUnauthorized)
+		return database.OauthClient{}, false
+	}
+	return client, true
+}
+
+func (cfg *apiConfig) handlerOAuthToken(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+		Scope       string `json:"scope"`
+	}
+
+	if err := req.ParseForm(); err != nil {
+		respondWithOAuthError(w, "invalid_request", "malformed token request", http.StatusBadRequest)
+		return
+	}
+	client, ok := cfg.authenticateOAuthClient(w, req)
+	if !ok {
+		return
+	}
+	if req.PostForm.Get("grant_type") != "authorization_code" {
+		respondWithOAuthError(w, "unsupported_grant_type", "only the authorization_code grant is supported", http.StatusBadRequest)
+		return
+	}
+
+	code, err := cfg.db.ConsumeOAuthAuthorizationCode(req.Context(), auth.HashToken(req.PostForm.Get("code")))
+	if err != nil {
+		respondWithOAuthError(w, "invalid_grant", "authorization code is invalid, expired or already used", http.StatusBadRequest)
+		return
+	}
+	if code.ClientID != client.ID {
+		respondWithOAuthError(w, "invalid_grant", "authorization code was issued to another client", http.StatusBadRequest)
+		return
+	}
+	if uri := req.PostForm.Get("redirect_uri"); uri != "" && uri != code.RedirectUri {
+		respondWithOAuthError(w, "invalid_grant", "redirect_uri does not match the authorization request", http.StatusBadRequest)
+		return
+	}
+	if !auth.VerifyPKCE(req.PostForm.Get("code_verifier"), code.CodeChallenge) {
+		respondWithOAuthError(w, "invalid_grant", "code_verifier does not match the code_challenge", http.StatusBadRequest)
+		return
+	}
+
+	raw, err := auth.MakeOAuthAccessToken()
+	if err != nil {
+		respondWithOAuthError(w, "server_error", "could not issue a token", http.StatusInternalServerError)
+		return
+	}
+	_, err = cfg.db.CreateAPIToken(req.Context(), database.CreateAPITokenParams{
+		UserID:    code.UserID,
+		Name:      client.Name,
+		TokenHash: auth.HashToken(raw),
+		Scopes:    code.Scopes,
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(oauthAccessTokenExpiry), Valid: true},
+		ClientID:  sql.NullString{String: client.ID, Valid: true},
+	})
+	if err != nil {
+		respondWithOAuthError(w, "server_error", "could not issue a token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, response{
+		AccessToken: raw,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(oauthAccessTokenExpiry.Seconds()),
+		Scope:       strings.Join(code.Scopes, " "),
+	}, http.StatusOK)
+}
+
+// handlerOAuthRevoke implements RFC 7009. Clients can only revoke their own
+// tokens, and unknown tokens are not an error.
+func (cfg *apiConfig) handlerOAuthRevoke(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		respondWithOAuthError(w, "invalid_request", "malformed revocation request", http.StatusBadRequest)
+		return
+	}
+	client, ok := cfg.authenticateOAuthClient(w, req)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.RevokeOAuthAccessToken(req.Context(), database.RevokeOAuthAccessTokenParams{
+		TokenHash: auth.HashToken(req.PostForm.Get("token")),
+		ClientID:  sql.NullString{String: client.ID, Valid: true},
+	})
+	if err != nil {
+		respondWithOAuthError(w, "server_error", "could not revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handlerOAuthIntrospect implements RFC 7662. A client only learns about
+// tokens issued to it; anything else is reported as inactive.
+func (cfg *apiConfig) handlerOAuthIntrospect(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		Subject   string `json:"sub,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		IssuedAt  int64  `json:"iat,omitempty"`
+		ExpiresAt int64  `json:"exp,omitempty"`
+	}
+
+	if err := req.ParseForm(); err != nil {
+		respondWithOAuthError(w, "invalid_request", "malformed introspection request", http.StatusBadRequest)
+		return
+	}
+	client, ok := cfg.authenticateOAuthClient(w, req)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	token, err := cfg.db.GetActiveAPITokenByHash(req.Context(), auth.HashToken(req.PostForm.Get("token")))
+	if err != nil || token.ClientID.String != client.ID {
+		respondWithJSON(w, response{Active: false}, http.StatusOK)
+		return
+	}
+
+	respondWithJSON(w, response{
+		Active:    true,
+		Scope:     strings.Join(token.Scopes, " "),
+		ClientID:  token.ClientID.String,
+		Subject:   token.UserID.String(),
+		TokenType: "Bearer",
+		IssuedAt:  token.CreatedAt.Unix(),
+		ExpiresAt: token.ExpiresAt.Time.Unix(),
+	}, http.StatusOK)
+}
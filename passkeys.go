@@ -0,0 +1,331 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/webauthn"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// relyingParty is who passkeys are registered with: WEBAUTHN_RP_ID is the
+// site's domain and WEBAUTHN_ORIGINS a comma separated list of the origins
+// the web app is served from.
+func relyingParty() webauthn.RelyingParty {
+	rp := webauthn.RelyingParty{
+		ID:      os.Getenv("WEBAUTHN_RP_ID"),
+		Name:    os.Getenv("WEBAUTHN_RP_NAME"),
+		Origins: []string{"http://localhost:" + port},
+	}
+	if rp.ID == "" {
+		rp.ID = "localhost"
+	}
+	if rp.Name == "" {
+		rp.Name = "Chirpy"
+	}
+	if s := os.Getenv("WEBAUTHN_ORIGINS"); s != "" {
+		rp.Origins = nil
+		for _, origin := range strings.Split(s, ",") {
+			rp.Origins = append(rp.Origins, strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		}
+	}
+	return rp
+}
+
+type Passkey struct {
+	ID         webauthn.Base64URL `json:"id"`
+	Name       string             `json:"name"`
+	CreatedAt  time.Time          `json:"created_at"`
+	LastUsedAt *time.Time         `json:"last_used_at"`
+}
+
+func passkeyFromDB(credential database.WebauthnCredential) Passkey {
+	return Passkey{
+		ID:         credential.ID,
+		Name:       credential.Name,
+		CreatedAt:  credential.CreatedAt,
+		LastUsedAt: timeOrNil(credential.LastUsedAt),
+	}
+}
+
+// newWebAuthnChallenge stores a fresh challenge for a ceremony and returns
+// it with the id the client sends back to finish the ceremony.
+func (cfg *apiConfig) newWebAuthnChallenge(req *http.Request, userID uuid.NullUUID, ceremony string) (uuid.UUID, []byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return uuid.UUID{}, nil, err
+	}
+	id, err := cfg.db.CreateWebAuthnChallenge(req.Context(), database.CreateWebAuthnChallengeParams{
+		UserID:    userID,
+		Challenge: challenge,
+		Ceremony:  ceremony,
+		ExpiresAt: time.Now().Add(webauthn.ChallengeTimeout),
+	})
+	return id, challenge, err
+}
+
+func (cfg *apiConfig) handlerBeginPasskeyRegistration(w http.ResponseWriter, req *http.Request) {
+	// only a real login can add a way to log in
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	type response struct {
+		ChallengeID uuid.UUID                `json:"challenge_id"`
+		PublicKey   webauthn.CreationOptions `json:"publicKey"`
+	}
+
+	user, err := cfg.db.GetUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	existing, err := cfg.db.ListWebAuthnCredentials(req.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	exclude := [][]byte{}
+	for _, credential := range existing {
+		exclude = append(exclude, credential.ID)
+	}
+
+	challengeID, challenge, err := cfg.newWebAuthnChallenge(req, uuid.NullUUID{UUID: user.ID, Valid: true}, ceremonyRegistration)
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	// the user handle is the user's id, which says nothing about who they are
+	options := cfg.relyingParty.CreationOptions(challenge, webauthn.User{
+		ID:          user.ID[:],
+		Name:        user.Email,
+		DisplayName: user.Email,
+	}, exclude)
+	respondWithJSON(w, response{ChallengeID: challengeID, PublicKey: options}, http.StatusOK)
+}
+
+func (cfg *apiConfig) handlerFinishPasskeyRegistration(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	type parameters struct {
+		ChallengeID uuid.UUID                     `json:"challenge_id"`
+		Name        string                        `json:"name"`
+		Credential  webauthn.RegistrationResponse `json:"credential"`
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed passkey registration", http.StatusBadRequest)
+		return
+	}
+	if params.Name == "" {
+		params.Name = "Passkey"
+	}
+
+	challenge, err := cfg.db.ConsumeWebAuthnChallenge(req.Context(), database.ConsumeWebAuthnChallengeParams{
+		ID:       params.ChallengeID,
+		Ceremony: ceremonyRegistration,
+	})
+	if err != nil || challenge.UserID.UUID != userID {
+		respondWithError(w, "invalid or expired challenge", http.StatusBadRequest)
+		return
+	}
+
+	credential, err := cfg.relyingParty.VerifyRegistration(params.Credential, challenge.Challenge)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := cfg.db.CreateWebAuthnCredential(req.Context(), database.CreateWebAuthnCredentialParams{
+		ID:         credential.ID,
+		UserID:     userID,
+		Name:       params.Name,
+		PublicKey:  credential.PublicKey,
+		SignCount:  int64(credential.SignCount),
+		Transports: credential.Transports,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, "passkey is already registered", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "Could not save passkey", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, passkeyFromDB(created), http.StatusCreated)
+}
+
+func (cfg *apiConfig) handlerListPasskeys(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	credentials, err := cfg.db.ListWebAuthnCredentials(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Could not list passkeys", http.StatusInternalServerError)
+		return
+	}
+
+	result := []Passkey{}
+	for _, credential := range credentials {
+		result = append(result, passkeyFromDB(credential))
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
+
+func (cfg *apiConfig) handlerDeletePasskey(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	passkeyID, err := base64.RawURLEncoding.DecodeString(req.PathValue("passkey_id"))
+	if err != nil {
+		respondWithError(w, "Invalid passkey ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteWebAuthnCredential(req.Context(), database.DeleteWebAuthnCredentialParams{
+		ID:     passkeyID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, "Passkey not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerBeginPasskeyLogin(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		ChallengeID uuid.UUID               `json:"challenge_id"`
+		PublicKey   webauthn.RequestOptions `json:"publicKey"`
+	}
+
+	challengeID, challenge, err := cfg.newWebAuthnChallenge(req, uuid.NullUUID{}, ceremonyLogin)
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	// passkeys are discoverable, so the browser finds the account itself
+	// and nothing here reveals whether an account exists
+	options := cfg.relyingParty.RequestOptions(challenge, nil)
+	respondWithJSON(w, response{ChallengeID: challengeID, PublicKey: options}, http.StatusOK)
+}
+
+// handlerFinishPasskeyLogin is an alternative to handlerLogin. A passkey
+// proves both possession of a device and, through user verification, a
+// PIN or biometric, so it skips the second factor. It also skips the
+// password lockout: a passkey can't be guessed, and someone guessing the
+// password shouldn't lock the owner out of their passkey.
+func (cfg *apiConfig) handlerFinishPasskeyLogin(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		ChallengeID      uuid.UUID                  `json:"challenge_id"`
+		Credential       webauthn.AssertionResponse `json:"credential"`
+		ExpiresInSeconds int                        `json:"expires_in_seconds"`
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed login form", http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := cfg.db.ConsumeWebAuthnChallenge(req.Context(), database.ConsumeWebAuthnChallengeParams{
+		ID:       params.ChallengeID,
+		Ceremony: ceremonyLogin,
+	})
+	if err != nil {
+		respondWithError(w, "invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+
+	stored, err := cfg.db.GetWebAuthnCredential(req.Context(), params.Credential.RawID)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if handle := params.Credential.Response.UserHandle; len(handle) != 0 && string(handle) != string(stored.UserID[:]) {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	signCount, err := cfg.relyingParty.VerifyAssertion(params.Credential, challenge.Challenge, webauthn.Credential{
+		ID:        stored.ID,
+		PublicKey: stored.PublicKey,
+		SignCount: uint32(stored.SignCount),
+	})
+	if errors.Is(err, webauthn.ErrSignCount) {
+		log.Printf("passkey %x of user %s sent a stale sign count; it may have been cloned", stored.ID, stored.UserID)
+	}
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	// conditional on the old count, so two logins racing with the same
+	// count can't both succeed
+	updated, err := cfg.db.UpdateWebAuthnSignCount(req.Context(), database.UpdateWebAuthnSignCountParams{
+		NewSignCount: int64(signCount),
+		ID:           stored.ID,
+		OldSignCount: stored.SignCount,
+	})
+	if err != nil || updated == 0 {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := cfg.db.GetUser(req.Context(), stored.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if cfg.requireEmailVerification && !user.EmailVerifiedAt.Valid {
+		respondWithError(w, "email address has not been verified", http.StatusForbidden)
+		return
+	}
+
+	cfg.issueSession(w, req, user, cfg.accessTokenExpiry(params.ExpiresInSeconds))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// passwordPolicy is the default policy with its limits overridable from the
+// environment, and the breach check enabled when BREACHED_PASSWORDS_PATH is
+// set.
+func passwordPolicy() auth.PasswordPolicy {
+	policy := auth.DefaultPasswordPolicy
+	limits := []struct {
+		env   string
+		value *int
+	}{
+		{"PASSWORD_MIN_LENGTH", &policy.MinLength},
+		{"PASSWORD_MAX_LENGTH", &policy.MaxLength},
+		{"PASSWORD_MIN_SCORE", &policy.MinScore},
+	}
+	for _, limit := range limits {
+		if s := os.Getenv(limit.env); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				log.Fatalf("Invalid %s: %s", limit.env, err)
+			}
+			*limit.value = n
+		}
+	}
+	if path := os.Getenv("BREACHED_PASSWORDS_PATH"); path != "" {
+		breached, err := auth.LoadBreachedPasswords(path)
+		if err != nil {
+			log.Fatal("Failed to load breached passwords:", err)
+		}
+		policy.Breached = breached
+	}
+	return policy
+}
+
+// passwordHashParams are the argon2id parameters new password hashes are
+// made with. Raising them upgrades existing hashes as users log in.
+func passwordHashParams() auth.PasswordParams {
+	params := auth.DefaultPasswordParams
+	settings := []struct {
+		env  string
+		bits int
+		set  func(uint64)
+	}{
+		{"PASSWORD_HASH_MEMORY", 32, func(n uint64) { params.Memory = uint32(n) }},
+		{"PASSWORD_HASH_ITERATIONS", 32, func(n uint64) { params.Iterations = uint32(n) }},
+		{"PASSWORD_HASH_PARALLELISM", 8, func(n uint64) { params.Parallelism = uint8(n) }},
+	}
+	for _, setting := range settings {
+		if s := os.Getenv(setting.env); s != "" {
+			n, err := strconv.ParseUint(s, 10, setting.bits)
+			if err != nil || n == 0 {
+				log.Fatalf("Invalid %s: must be a positive integer", setting.env)
+			}
+			setting.set(n)
+		}
+	}
+	return params
+}
+
+// upgradePasswordHash rehashes a password that was just verified if its
+// stored hash is weaker than the current parameters. It only replaces the
+// hash it checked against, so a password changed in the meantime is kept.
+func (cfg *apiConfig) upgradePasswordHash(ctx context.Context, user database.User, password string) {
+	stale, err := auth.NeedsRehash(user.HashedPassword, cfg.passwordParams)
+	if err != nil || !stale {
+		return
+	}
+	hashed, err := auth.HashPasswordWithParams(password, cfg.passwordParams)
+	if err != nil {
+		log.Printf("failed to rehash password of user %s: %s", user.ID, err)
+		return
+	}
+	_, err = cfg.db.UpgradeUserPasswordHash(ctx, database.UpgradeUserPasswordHashParams{
+		NewHash: hashed,
+		ID:      user.ID,
+		OldHash: user.HashedPassword,
+	})
+	if err != nil {
+		log.Printf("failed to store rehashed password of user %s: %s", user.ID, err)
+	}
+}
+
+type PasswordPolicyError struct {
+	Error      string                   `json:"error"`
+	Violations []auth.PasswordViolation `json:"violations"`
+}
+
+// checkPasswordPolicy responds with the broken rules and returns false when
+// password isn't allowed. userInputs are the user's own details, like their
+// email, that the password shouldn't be built from.
+func (cfg *apiConfig) checkPasswordPolicy(w http.ResponseWriter, password string, userInputs ...string) bool {
+	violations, err := cfg.passwordPolicy.Check(password, userInputs...)
+	if err != nil {
+		log.Printf("failed to check password policy: %s", err)
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return false
+	}
+	if len(violations) > 0 {
+		respondWithJSON(w, PasswordPolicyError{
+			Error:      "password does not meet the password policy",
+			Violations: violations,
+		}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/mailer"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+const passwordResetExpiry = 1 * time.Hour
+
+func (cfg *apiConfig) handlerForgotPassword(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed password reset request", http.StatusBadRequest)
+		return
+	}
+
+	// the response never depends on whether the account exists, and the
+	// slow part happens in the background so timing doesn't give it away either
+	go cfg.sendPasswordReset(context.WithoutCancel(req.Context()), params.Email)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, email string) {
+	user, err := cfg.db.GetUserByEmail(ctx, email)
+	if err != nil {
+		return
+	}
+
+	token, _ := auth.MakeRefreshToken()
+	err = cfg.db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(passwordResetExpiry),
+	})
+	if err != nil {
+		log.Printf("failed to store password reset token for user %s: %s", user.ID, err)
+		return
+	}
+
+	err = cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: "Someone asked to reset the password for your Chirpy account.\n\n" +
+			"To choose a new password, send the token below to POST /api/password/reset.\n" +
+			"It expires in 1 hour and can only be used once. If this wasn't you, you can ignore this email.\n\n" +
+			token + "\n",
+	})
+	if err != nil {
+		log.Printf("failed to send password reset email to user %s: %s", user.ID, err)
+	}
+}
+
+func (cfg *apiConfig) handlerResetPassword(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed password reset request", http.StatusBadRequest)
+		return
+	}
+
+	// the policy is checked before the token is used up, so a rejected
+	// password can be retried
+	owner, err := cfg.db.GetPasswordResetTokenUser(req.Context(), auth.HashToken(params.Token))
+	if err != nil {
+		respondWithError(w, "invalid or expired reset token", http.StatusBadRequest)
+		return
+	}
+	if !cfg.checkPasswordPolicy(w, params.Password, owner.Email) {
+		return
+	}
+
+	userID, err := cfg.db.ConsumePasswordResetToken(req.Context(), auth.HashToken(params.Token))
+	if err != nil {
+		respondWithError(w, "invalid or expired reset token", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := auth.HashPasswordWithParams(params.Password, cfg.passwordParams)
+	if err != nil {
+		respondWithError(w, "failed to hash password", http.StatusInternalServerError)
+		return
+	}
+	user, err := cfg.db.UpdateUserPassword(req.Context(), database.UpdateUserPasswordParams{
+		ID:             userID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		respondWithError(w, "failed to update password", http.StatusInternalServerError)
+		return
+	}
+
+	// proving control of the mailbox is enough to lift a lockout
+	if err := cfg.clearLoginFailures(req.Context(), user.Email); err != nil {
+		log.Printf("failed to clear login failures for user %s: %s", user.ID, err)
+	}
+
+	// whoever had the old password may also have a session
+	if err := cfg.revokeAllSessions(req.Context(), userID); err != nil {
+		respondWithError(w, "failed to revoke existing sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// principal is who a request acts for and what it may do.
+type principal struct {
+	UserID uuid.UUID
+	// nil for the user's own access token, which can do anything the user can
+	Scopes []string
+	// set when an OAuth client acts on the user's behalf
+	ClientID string
+}
+
+func (p principal) can(scope string) bool {
+	return p.Scopes == nil || slices.Contains(p.Scopes, scope)
+}
+
+// authenticate accepts a JWT access token, a personal access token or an
+// OAuth access token as the bearer token.
+func (cfg *apiConfig) authenticate(req *http.Request) (principal, error) {
+	token := auth.GetBearerToken(req.Header)
+	if !auth.IsAPIToken(token) {
+		userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+		if err != nil {
+			return principal{}, err
+		}
+		return principal{UserID: userID}, nil
+	}
+
+	apiToken, err := cfg.db.GetActiveAPITokenByHash(req.Context(), auth.HashToken(token))
+	if err != nil {
+		return principal{}, errors.New("api token is invalid, expired or revoked")
+	}
+	if err := cfg.db.TouchAPIToken(req.Context(), apiToken.ID); err != nil {
+		log.Printf("failed to record use of api token %s: %s", apiToken.ID, err)
+	}
+	return principal{
+		UserID:   apiToken.UserID,
+		Scopes:   apiToken.Scopes,
+		ClientID: apiToken.ClientID.String,
+	}, nil
+}
+
+// authorize authenticates req and checks it was granted scope. When it
+// returns false it has already responded with 401 or 403.
+func (cfg *apiConfig) authorize(w http.ResponseWriter, req *http.Request, scope string) (principal, bool) {
+	p, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return principal{}, false
+	}
+	if !p.can(scope) {
+		respondWithError(w, "token is missing the "+scope+" scope", http.StatusForbidden)
+		return principal{}, false
+	}
+	return p, true
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const (
+	chirpKindChirp   = "chirp"
+	chirpKindRechirp = "rechirp"
+	chirpKindQuote   = "quote"
+)
+
+// originalOf is the chirp to rechirp, quote or reply to when a user picks
+// chirp: a rechirp stands for the chirp it rechirps.
+func originalOf(chirp database.Chirp) uuid.UUID {
+	if chirp.Kind == chirpKindRechirp {
+		return chirp.RechirpOf.UUID
+	}
+	return chirp.ID
+}
+
+// resolveOriginal looks up the chirp with id and returns the ID of the
+// chirp it stands for, so that acting on a rechirp acts on the chirp it
+// shares.
+func (cfg *apiConfig) resolveOriginal(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	chirp, err := cfg.db.GetChirp(ctx, id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return originalOf(chirp), nil
+}
+
+// renderChirps converts chirps for a response. Rechirps and quotes get the
+// chirp they point at embedded as original, and every chirp, embedded or
+// not, gets its entities and is marked with whether the user making req
+// has liked it.
+func (cfg *apiConfig) renderChirps(req *http.Request, chirps []database.Chirp) ([]Chirp, error) {
+	result := make([]Chirp, 0, len(chirps))
+	ids := []uuid.UUID{}
+	originalIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		rendered := chirpFromDB(chirp)
+		result = append(result, rendered)
+		ids = append(ids, rendered.ID)
+		if rendered.OriginalID != nil {
+			originalIDs = append(originalIDs, *rendered.OriginalID)
+		}
+	}
+
+	originals := map[uuid.UUID]Chirp{}
+	if len(originalIDs) > 0 {
+		rows, err := cfg.db.GetChirpsByIDs(req.Context(), originalIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			originals[row.ID] = chirpFromDB(row)
+			ids = append(ids, row.ID)
+		}
+	}
+
+	mentions, err := cfg.chirpMentions(req.Context(), ids)
+	if err != nil {
+		return nil, err
+	}
+	liked := cfg.likedByMe(req, ids)
+	for i := range result {
+		result[i].Entities = chirpEntities(result[i].Body, mentions[result[i].ID])
+		result[i].LikedByMe = liked[result[i].ID]
+		if result[i].OriginalID == nil {
+			continue
+		}
+		if original, ok := originals[*result[i].OriginalID]; ok {
+			original.Entities = chirpEntities(original.Body, mentions[original.ID])
+			original.LikedByMe = liked[original.ID]
+			result[i].Original = &original
+		}
+	}
+	return result, nil
+}
+
+// respondWithChirp renders a single chirp the way renderChirps does.
+func (cfg *apiConfig) respondWithChirp(w http.ResponseWriter, req *http.Request, chirp database.Chirp, status int) {
+	rendered, err := cfg.renderChirps(req, []database.Chirp{chirp})
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, rendered[0], status)
+}
+
+// handlerRechirp shares a chirp with the caller's followers as it is. To
+// add words of their own, users post a chirp with quote_of instead.
+func (cfg *apiConfig) handlerRechirp(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	user, err := cfg.db.GetUser(req.Context(), p.UserID)
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if cfg.requireEmailVerification && !user.EmailVerifiedAt.Valid {
+		respondWithError(w, "email address has not been verified", http.StatusForbidden)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+	rechirp, err := cfg.db.CreateRechirp(req.Context(), database.CreateRechirpParams{
+		UserID:    user.ID,
+		RechirpOf: uuid.NullUUID{UUID: originalOf(chirp), Valid: true},
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, "chirp is already rechirped", http.StatusConflict)
+		return
+	}
+	if isForeignKeyViolation(err) {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "Could not rechirp", http.StatusInternalServerError)
+		return
+	}
+
+	cfg.respondWithChirp(w, req, rechirp, http.StatusCreated)
+}
+
+func (cfg *apiConfig) handlerUndoRechirp(w http.ResponseWriter, req *http.Request) {
+	p, ok := cfg.authorize(w, req, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	original, err := cfg.resolveOriginal(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+	deleted, err := cfg.db.DeleteRechirp(req.Context(), database.DeleteRechirpParams{
+		UserID:    p.UserID,
+		RechirpOf: uuid.NullUUID{UUID: original, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, "Could not undo rechirp", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, "chirp is not rechirped", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
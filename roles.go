@@ -0,0 +1,118 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// middlewareRequireRole only lets through users whose role is at least role.
+// Requests carrying ADMIN_API_KEY are let through too, for automation.
+func (cfg *apiConfig) middlewareRequireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		apiKey := auth.GetAPIKey(req.Header)
+		if cfg.adminAPIKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.adminAPIKey)) == 1 {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		// personal and OAuth tokens never carry a role
+		userID, claimed, err := auth.ValidateAccessToken(auth.GetBearerToken(req.Header), cfg.signer)
+		if err != nil {
+			respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		if !auth.HasRole(claimed, role) {
+			respondWithError(w, "requires the "+role+" role", http.StatusForbidden)
+			return
+		}
+		// the claim can be an access token's lifetime out of date, so check
+		// again to make a demotion take effect straight away
+		user, err := cfg.db.GetUser(req.Context(), userID)
+		if err != nil || !auth.HasRole(user.Role, role) {
+			respondWithError(w, "requires the "+role+" role", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (cfg *apiConfig) handlerSetUserRole(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	type response struct {
+		User
+		Role string `json:"role"`
+	}
+
+	userID, err := uuid.Parse(req.PathValue("user_id"))
+	if err != nil {
+		respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	// stops the last admin from demoting themselves by accident
+	callerID, _, _ := auth.ValidateAccessToken(auth.GetBearerToken(req.Header), cfg.signer)
+	if callerID == userID {
+		respondWithError(w, "you can't change your own role", http.StatusBadRequest)
+		return
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed role change", http.StatusBadRequest)
+		return
+	}
+	if !auth.ValidRole(params.Role) {
+		respondWithError(w, "role must be user, moderator or admin", http.StatusBadRequest)
+		return
+	}
+
+	user, err := cfg.db.SetUserRole(req.Context(), database.SetUserRoleParams{
+		ID:   userID,
+		Role: params.Role,
+	})
+	if err != nil {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	respondWithJSON(w, response{
+		User: User{
+			ID:          user.ID,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+			Email:       user.Email,
+			IsChirpyRed: user.IsChirpyRed.Bool,
+			Handle:      stringOrNil(user.Handle),
+		},
+		Role: user.Role,
+	}, http.StatusOK)
+}
+
+// handlerModerateDeleteChirp lets moderators remove anyone's chirp.
+func (cfg *apiConfig) handlerModerateDeleteChirp(w http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteChirpByID(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, "Could not delete chirp", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"chirpy/internal/search"
+	"database/sql"
+	"net/http"
+	"time"
+)
+
+// maxSearchQueryLength keeps search queries to a few chirps' worth of text.
+const maxSearchQueryLength = 500
+
+// handlerSearchChirps searches chirps by the query in q, best matches
+// first, a page at a time. See package search for the query language.
+func (cfg *apiConfig) handlerSearchChirps(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query().Get("q")
+	if len(q) > maxSearchQueryLength {
+		respondWithError(w, "Search query is too long", http.StatusBadRequest)
+		return
+	}
+	query, err := search.Parse(q)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query(), defaultPageSize, maxPageSize)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := database.SearchChirpsParams{
+		Text:             query.Text,
+		FromHandle:       nullString(query.From),
+		Since:            nullTime(query.Since),
+		Until:            nullTime(query.Until),
+		Hashtags:         query.Hashtags,
+		ExcludedHashtags: query.ExcludedHashtags,
+		PageSize:         int32(limit + 1),
+	}
+	if s := req.URL.Query().Get("cursor"); s != "" {
+		after, err := pagination.DecodeRanked(s)
+		if err != nil {
+			respondWithError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.BeforeRank = sql.NullFloat64{Float64: float64(after.Rank), Valid: true}
+		params.BeforeCreatedAt, params.BeforeID = cursorArgs(&after.Cursor)
+	}
+
+	rows, err := cfg.db.SearchChirps(req.Context(), params)
+	if err != nil {
+		respondWithError(w, "Could not search chirps", http.StatusInternalServerError)
+		return
+	}
+
+	rows, more := pagination.Trim(rows, limit)
+	chirps := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = searchChirp(row)
+	}
+	result, err := cfg.renderChirps(req, chirps)
+	if err != nil {
+		respondWithError(w, "Could not search chirps", http.StatusInternalServerError)
+		return
+	}
+	if more {
+		last := rows[len(rows)-1]
+		pagination.SetNextLink(w, req, pagination.RankedCursor{
+			Rank:   last.Rank,
+			Cursor: pagination.Cursor{CreatedAt: last.CreatedAt.Time, ID: last.ID},
+		})
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
+
+func searchChirp(row database.SearchChirpsRow) database.Chirp {
+	return database.Chirp{
+		ID:         row.ID,
+		CreatedAt:  row.CreatedAt,
+		UpdatedAt:  row.UpdatedAt,
+		Body:       row.Body,
+		UserID:     row.UserID,
+		EditedAt:   row.EditedAt,
+		InReplyTo:  row.InReplyTo,
+		ReplyCount: row.ReplyCount,
+		LikeCount:  row.LikeCount,
+		Kind:       row.Kind,
+		RechirpOf:  row.RechirpOf,
+		QuoteOf:    row.QuoteOf,
+	}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
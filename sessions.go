@@ -0,0 +1,126 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is one login: the family of refresh tokens rotated from it.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+func (cfg *apiConfig) handlerListSessions(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := cfg.db.ListActiveSessions(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Could not list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	result := []Session{}
+	for _, session := range sessions {
+		result = append(result, Session{
+			ID:         session.ID,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			UserAgent:  session.UserAgent.String,
+			IPAddress:  session.IpAddress.String,
+		})
+	}
+
+	respondWithJSON(w, result, http.StatusOK)
+}
+
+func (cfg *apiConfig) handlerRevokeSession(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(req.PathValue("session_id"))
+	if err != nil {
+		respondWithError(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := cfg.revokeSession(req.Context(), userID, sessionID); err != nil {
+		respondWithError(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerRevokeAllSessions(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if err := cfg.revokeAllSessions(req.Context(), userID); err != nil {
+		respondWithError(w, "Could not revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeSession ends one of userID's sessions along with every refresh token
+// in it. Access tokens already issued stay valid until they expire.
+func (cfg *apiConfig) revokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	err := cfg.db.RevokeRefreshTokenFamily(ctx, database.RevokeRefreshTokenFamilyParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cfg.db.RevokeSession(ctx, database.RevokeSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	return err
+}
+
+func (cfg *apiConfig) revokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	if err := cfg.db.RevokeAllSessionsForUser(ctx, userID); err != nil {
+		return err
+	}
+	return cfg.db.RevokeAllRefreshTokensForUser(ctx, userID)
+}
+
+// clientIP is the address of the connecting peer. Forwarding headers are
+// ignored because any client can set them.
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,31 @@
+-- name: ScheduleUserDeletion :one
+update users
+set deletion_requested_at = coalesce(deletion_requested_at, now()),
+    delete_after = coalesce(delete_after, $2)
+where id = $1
+returning delete_after;
+
+-- name: CancelUserDeletion :exec
+update users
+set deletion_requested_at = null, delete_after = null
+where id = $1;
+
+-- name: DeleteDueUsers :many
+with due as (
+  select id from users
+  where delete_after <= now()
+  order by delete_after
+  limit $1
+  for update skip locked
+), deleted as (
+  delete from users
+  where id in (select id from due)
+  returning id, email, created_at, deletion_requested_at
+), forgotten_attempts as (
+  delete from login_attempts
+  where key in (select 'account:' || lower(email) from deleted)
+)
+insert into deleted_users (id, email_hash, created_at, deletion_requested_at)
+select id, encode(sha256(convert_to(lower(email), 'UTF8')), 'hex'), created_at, deletion_requested_at
+from deleted
+returning id;
@@ -0,0 +1,44 @@
+-- name: CreateAPIToken :one
+insert into api_tokens (
+  user_id, name, token_hash, scopes, expires_at, client_id
+) values (
+  $1, $2, $3, $4, $5, $6
+) returning *;
+
+-- name: GetActiveAPITokenByHash :one
+select * from api_tokens
+where token_hash = $1
+  and revoked_at is null
+  and (expires_at is null or now() < expires_at)
+  and not exists (
+    select 1 from users
+    where users.id = api_tokens.user_id and users.delete_after is not null
+  )
+limit 1;
+
+-- name: ListAPITokens :many
+select * from api_tokens
+where user_id = $1 and client_id is null and revoked_at is null
+order by created_at desc;
+
+-- name: TouchAPIToken :exec
+update api_tokens
+set last_used_at = now()
+where id = $1;
+
+-- name: RevokeAPIToken :one
+update api_tokens
+set revoked_at = now()
+where id = $1 and user_id = $2 and revoked_at is null
+returning id;
+
+-- name: RevokeAllAPITokensForUser :exec
+-- Personal access tokens and OAuth access tokens alike, along with
+-- authorization codes that could still be exchanged for new ones.
+with discarded_codes as (
+  delete from oauth_authorization_codes
+  where user_id = $1 and used_at is null
+)
+update api_tokens
+set revoked_at = now()
+where user_id = $1 and revoked_at is null;
@@ -0,0 +1,37 @@
+-- name: BookmarkChirp :exec
+insert into bookmarks (user_id, chirp_id, folder_id)
+values ($1, $2, $3)
+on conflict (user_id, chirp_id) do update set folder_id = excluded.folder_id;
+
+-- name: DeleteBookmark :execrows
+delete from bookmarks
+where user_id = $1 and chirp_id = $2;
+
+-- name: ListBookmarks :many
+select chirps.*, bookmarks.created_at as bookmarked_at, bookmarks.folder_id
+from bookmarks
+join chirps on chirps.id = bookmarks.chirp_id
+where bookmarks.user_id = @user_id
+  and (sqlc.narg('folder_id')::uuid is null or bookmarks.folder_id = sqlc.narg('folder_id')::uuid)
+  and (sqlc.narg('before_created_at')::timestamp is null
+    or (bookmarks.created_at, bookmarks.chirp_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+order by bookmarks.created_at desc, bookmarks.chirp_id desc
+limit @page_size;
+
+-- name: CreateBookmarkFolder :one
+insert into bookmark_folders (user_id, name)
+values ($1, $2)
+returning *;
+
+-- name: GetBookmarkFolder :one
+select * from bookmark_folders
+where id = $1 and user_id = $2;
+
+-- name: ListBookmarkFolders :many
+select * from bookmark_folders
+where user_id = $1
+order by name;
+
+-- name: DeleteBookmarkFolder :execrows
+delete from bookmark_folders
+where id = $1 and user_id = $2;
@@ -1,18 +1,151 @@
 -- name: CreateChirp :one
-insert into chirps (
-  body, user_id
-) values (
-  $1, $2
-) returning *;
-
--- name: GetAllChirps :many
-select * from chirps order by created_at;
-
--- name: GetChirpsByAuthor :many
-select * from chirps where user_id = $1 order by created_at;
+with chirp as (
+  insert into chirps (
+    body, user_id, in_reply_to, kind, quote_of
+  ) values (
+    @body, @user_id, @in_reply_to, @kind, @quote_of
+  ) returning *
+), hashtags as (
+  insert into chirp_hashtags (chirp_id, tag, created_at)
+  select chirp.id, tag, chirp.created_at
+  from chirp, unnest(@hashtags::text[]) as tag
+), mentions as (
+  insert into chirp_mentions (chirp_id, handle, user_id)
+  select chirp.id, lower(users.handle), users.id
+  from chirp, users
+  where lower(users.handle) = any(@mentions::text[])
+  returning user_id
+), notified as (
+  insert into notifications (user_id, kind, actor_id, chirp_id)
+  select mentions.user_id, 'mention', chirp.user_id, chirp.id
+  from chirp, mentions
+  where mentions.user_id <> chirp.user_id
+)
+select * from chirp;
 
 -- name: GetChirp :one
 select * from chirps where id = $1 limit 1;
 
 -- name: DeleteChirp :exec
 delete from chirps where id = $1 and user_id = $2;
+
+-- name: DeleteChirpByID :execrows
+delete from chirps where id = $1;
+
+-- name: EditChirp :one
+with previous as (
+  select id, user_id, body, created_at, coalesce(edited_at, created_at) as written_at
+  from chirps
+  where id = @id and user_id = @user_id
+  for update
+), revision as (
+  insert into chirp_revisions (chirp_id, body, written_at)
+  select id, body, written_at from previous
+), removed_hashtags as (
+  delete from chirp_hashtags
+  where chirp_id = (select id from previous) and tag <> all(@hashtags::text[])
+), added_hashtags as (
+  insert into chirp_hashtags (chirp_id, tag, created_at)
+  select previous.id, tag, previous.created_at
+  from previous, unnest(@hashtags::text[]) as tag
+  on conflict do nothing
+), removed_mentions as (
+  delete from chirp_mentions
+  where chirp_id = (select id from previous) and handle <> all(@mentions::text[])
+), added_mentions as (
+  -- handles mentioned before keep the user they were resolved to
+  insert into chirp_mentions (chirp_id, handle, user_id)
+  select previous.id, lower(users.handle), users.id
+  from previous, users
+  where lower(users.handle) = any(@mentions::text[])
+  on conflict do nothing
+  returning user_id
+), notified as (
+  insert into notifications (user_id, kind, actor_id, chirp_id)
+  select added_mentions.user_id, 'mention', previous.user_id, previous.id
+  from previous, added_mentions
+  where added_mentions.user_id <> previous.user_id
+    -- toggling a mention off and on again mustn't notify anyone twice
+    and not exists (
+      select 1 from notifications
+      where notifications.chirp_id = previous.id
+        and notifications.user_id = added_mentions.user_id
+        and notifications.kind = 'mention'
+    )
+)
+update chirps
+set body = @body, edited_at = now(), updated_at = now()
+from previous
+where chirps.id = previous.id
+returning chirps.*;
+
+-- name: ListChirpRevisions :many
+select * from chirp_revisions
+where chirp_id = $1
+order by replaced_at;
+
+-- name: GetChirpAncestors :many
+with recursive ancestors as (
+  select parent.*, 1 as depth
+  from chirps parent
+  join chirps child on child.in_reply_to = parent.id
+  where child.id = $1
+  union all
+  select parent.*, ancestors.depth + 1
+  from chirps parent
+  join ancestors on ancestors.in_reply_to = parent.id
+)
+select id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of
+from ancestors
+order by depth desc;
+
+-- name: GetChirpDescendants :many
+-- Pages are keyed on the path of the last reply on the previous page, so
+-- replies posted while a client pages don't shift the rest.
+with recursive descendants as (
+  select chirps.*, 1 as depth,
+    array[to_char(created_at, 'YYYYMMDDHH24MISSUS') || id::text] as path
+  from chirps
+  where in_reply_to = @in_reply_to
+  union all
+  select chirps.*, descendants.depth + 1,
+    descendants.path || (to_char(chirps.created_at, 'YYYYMMDDHH24MISSUS') || chirps.id::text)
+  from chirps
+  join descendants on chirps.in_reply_to = descendants.id
+)
+select id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of, depth
+from descendants
+where sqlc.narg('after_id')::uuid is null
+  or path > (select path from descendants where id = sqlc.narg('after_id')::uuid)
+order by path
+limit @page_size;
+
+-- name: GetChirpsByIDs :many
+select * from chirps where id = any(@ids::uuid[]);
+
+-- name: CreateRechirp :one
+insert into chirps (
+  body, user_id, kind, rechirp_of
+) values (
+  '', $1, 'rechirp', $2
+) returning *;
+
+-- name: DeleteRechirp :execrows
+delete from chirps where user_id = $1 and rechirp_of = $2;
+
+-- name: ListChirps :many
+-- Oldest first, optionally by one author.
+select * from chirps
+where (sqlc.narg('author_id')::uuid is null or user_id = sqlc.narg('author_id')::uuid)
+  and (sqlc.narg('after_created_at')::timestamp is null
+    or (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+order by created_at, id
+limit @page_size;
+
+-- name: ListChirpsNewestFirst :many
+select * from chirps
+where (sqlc.narg('author_id')::uuid is null or user_id = sqlc.narg('author_id')::uuid)
+  and (sqlc.narg('before_created_at')::timestamp is null
+    or (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+order by created_at desc, id desc
+limit @page_size;
@@ -0,0 +1,12 @@
+-- name: CreateEmailVerification :one
+insert into email_verifications (
+  user_id, email, expires_at
+) values (
+  $1, $2, $3
+) returning id;
+
+-- name: ConsumeEmailVerification :one
+update email_verifications
+set used_at = now()
+where id = $1 and used_at is null and now() < expires_at
+returning user_id, email;
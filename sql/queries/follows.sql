@@ -0,0 +1,44 @@
+-- name: FollowUser :execrows
+insert into follows (follower_id, followee_id)
+values ($1, $2)
+on conflict do nothing;
+
+-- name: UnfollowUser :execrows
+delete from follows
+where follower_id = $1 and followee_id = $2;
+
+-- name: ListFollowers :many
+select follower_id as user_id, created_at from follows
+where followee_id = @user_id
+  and (sqlc.narg('before_created_at')::timestamp is null
+    or (created_at, follower_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+order by created_at desc, follower_id desc
+limit @page_size;
+
+-- name: ListFollowing :many
+select followee_id as user_id, created_at from follows
+where follower_id = @user_id
+  and (sqlc.narg('before_created_at')::timestamp is null
+    or (created_at, followee_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+order by created_at desc, followee_id desc
+limit @page_size;
+
+-- name: GetTimeline :many
+-- Reads at most page_size chirps from each author, newest first, straight
+-- off chirps_user_id_created_at_idx, then merges them. The work depends on
+-- how many people the user follows, not on how much they have all chirped.
+select timeline.* from (
+  select followee_id as author_id from follows where follower_id = @user_id
+  union all
+  select @user_id
+) authors
+cross join lateral (
+  select * from chirps
+  where chirps.user_id = authors.author_id
+    and (sqlc.narg('before_created_at')::timestamp is null
+      or (chirps.created_at, chirps.id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+  order by chirps.created_at desc, chirps.id desc
+  limit @page_size
+) timeline
+order by timeline.created_at desc, timeline.id desc
+limit @page_size;
@@ -0,0 +1,42 @@
+-- name: ListHashtagChirps :many
+select chirps.* from chirp_hashtags
+join chirps on chirps.id = chirp_hashtags.chirp_id
+where chirp_hashtags.tag = @tag
+  and (sqlc.narg('before_created_at')::timestamp is null
+    or (chirp_hashtags.created_at, chirp_hashtags.chirp_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+order by chirp_hashtags.created_at desc, chirp_hashtags.chirp_id desc
+limit @page_size;
+
+-- name: RefreshTrendingHashtags :exec
+-- Each use of a tag scores 1, halving every half life, and each user
+-- counts once per tag, by their latest use, so nobody can push a tag up
+-- alone.
+with uses as (
+  select chirp_hashtags.tag, chirps.user_id,
+    max(chirp_hashtags.created_at) as used_at, count(*) as chirp_count
+  from chirp_hashtags
+  join chirps on chirps.id = chirp_hashtags.chirp_id
+  where chirp_hashtags.created_at > now() - make_interval(secs => @window_seconds::float8)
+  group by chirp_hashtags.tag, chirps.user_id
+), scores as (
+  select tag,
+    sum(power(0.5::float8, extract(epoch from now()::timestamp - used_at)::float8 / @half_life_seconds::float8)) as score,
+    sum(chirp_count)::integer as chirp_count
+  from uses
+  group by tag
+  order by score desc
+  limit @max_tags
+), refreshed as (
+  insert into trending_hashtags (tag, score, chirp_count, computed_at)
+  select tag, score, chirp_count, now() from scores
+  on conflict (tag) do update
+  set score = excluded.score, chirp_count = excluded.chirp_count, computed_at = excluded.computed_at
+  returning tag
+)
+delete from trending_hashtags
+where tag not in (select tag from refreshed);
+
+-- name: ListTrendingHashtags :many
+select * from trending_hashtags
+order by score desc, tag
+limit $1;
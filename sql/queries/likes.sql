@@ -0,0 +1,22 @@
+-- name: LikeChirp :execrows
+insert into chirp_likes (user_id, chirp_id)
+values ($1, $2)
+on conflict do nothing;
+
+-- name: UnlikeChirp :execrows
+delete from chirp_likes
+where user_id = $1 and chirp_id = $2;
+
+-- name: ListLikedChirpIDs :many
+select chirp_id from chirp_likes
+where user_id = @user_id and chirp_id = any(@chirp_ids::uuid[]);
+
+-- name: ListUserLikes :many
+select chirps.*, chirp_likes.created_at as liked_at
+from chirp_likes
+join chirps on chirps.id = chirp_likes.chirp_id
+where chirp_likes.user_id = @user_id
+  and (sqlc.narg('before_created_at')::timestamp is null
+    or (chirp_likes.created_at, chirp_likes.chirp_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+order by chirp_likes.created_at desc, chirp_likes.chirp_id desc
+limit @page_size;
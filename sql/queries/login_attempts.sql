@@ -0,0 +1,25 @@
+-- name: GetLoginAttempt :one
+select * from login_attempts where key = $1 limit 1;
+
+-- name: RecordLoginFailure :one
+insert into login_attempts (
+  key, failures, last_failure_at
+) values (
+  @key, 1, now()
+)
+on conflict (key) do update
+set
+  failures = case
+    when login_attempts.last_failure_at < @window_start::timestamp then 1
+    else login_attempts.failures + 1
+  end,
+  last_failure_at = now()
+returning failures;
+
+-- name: LockLogin :exec
+update login_attempts
+set locked_until = $2
+where key = $1;
+
+-- name: ClearLoginAttempts :exec
+delete from login_attempts where key = $1;
@@ -0,0 +1,15 @@
+-- name: CreateMagicLinkToken :execrows
+insert into magic_link_tokens (
+  token_hash, user_id, email, expires_at
+)
+select $1, $2, $3, $4
+where not exists (
+  select 1 from magic_link_tokens
+  where user_id = $2 and created_at > now() - interval '1 minute'
+);
+
+-- name: ConsumeMagicLinkToken :one
+update magic_link_tokens
+set used_at = now()
+where token_hash = $1 and used_at is null and now() < expires_at
+returning user_id, email;
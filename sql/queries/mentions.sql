@@ -0,0 +1,3 @@
+-- name: ListChirpMentions :many
+select * from chirp_mentions
+where chirp_id = any(@chirp_ids::uuid[]);
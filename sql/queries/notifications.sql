@@ -0,0 +1,12 @@
+-- name: ListNotifications :many
+select * from notifications
+where user_id = @user_id
+  and (sqlc.narg('before_created_at')::timestamp is null
+    or (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+order by created_at desc, id desc
+limit @page_size;
+
+-- name: MarkNotificationsRead :execrows
+update notifications
+set read_at = now()
+where user_id = $1 and read_at is null;
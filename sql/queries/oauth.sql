@@ -0,0 +1,30 @@
+-- name: CreateOAuthClient :one
+insert into oauth_clients (
+  id, owner_id, name, secret_hash, redirect_uris
+) values (
+  $1, $2, $3, $4, $5
+) returning *;
+
+-- name: GetOAuthClient :one
+select * from oauth_clients
+where id = $1;
+
+-- name: CreateOAuthAuthorizationCode :exec
+insert into oauth_authorization_codes (
+  code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at
+) values (
+  $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: ConsumeOAuthAuthorizationCode :one
+update oauth_authorization_codes
+set used_at = now()
+where code_hash = $1
+  and used_at is null
+  and now() < expires_at
+returning *;
+
+-- name: RevokeOAuthAccessToken :exec
+update api_tokens
+set revoked_at = now()
+where token_hash = $1 and client_id = $2 and revoked_at is null;
@@ -0,0 +1,32 @@
+-- name: CreatePasswordResetToken :exec
+-- A new token replaces any the user hasn't used yet.
+with discarded as (
+  delete from password_reset_tokens
+  where user_id = $2 and used_at is null
+)
+insert into password_reset_tokens (
+  token_hash, user_id, expires_at
+) values (
+  $1, $2, $3
+);
+
+-- name: GetPasswordResetTokenUser :one
+select users.id, users.email from password_reset_tokens
+join users on users.id = password_reset_tokens.user_id
+where password_reset_tokens.token_hash = $1
+  and password_reset_tokens.used_at is null
+  and now() < password_reset_tokens.expires_at;
+
+-- name: ConsumePasswordResetToken :one
+-- Using a token throws away the user's other unused ones.
+with consumed as (
+  update password_reset_tokens
+  set used_at = now()
+  where token_hash = $1 and used_at is null and now() < expires_at
+  returning user_id
+), discarded as (
+  delete from password_reset_tokens
+  where user_id in (select user_id from consumed)
+    and used_at is null and token_hash <> $1
+)
+select user_id from consumed;
@@ -1,18 +1,31 @@
--- name: CreateRefreshToken :one
+-- name: CreateRefreshTokenInFamily :one
 insert into refresh_tokens (
-  token, user_id
+  token, user_id, family_id, parent_token
 ) values (
-  $1, $2
+  $1, $2, $3, $4
 ) returning token;
 
--- name: ValidateRefreshToken :one
-select (revoked_at is null and now() < expires_at) as valid, user_id
-from refresh_tokens where token = $1 limit 1;
+-- name: GetRefreshToken :one
+select * from refresh_tokens where token = $1 limit 1;
 
--- name: RevokeRefreshToken :exec
+-- name: RotateRefreshToken :one
 update refresh_tokens
 set
   revoked_at = now(),
   updated_at = now()
-where token = $1
+where token = $1 and revoked_at is null
 returning token;
+
+-- name: RevokeRefreshTokenFamily :exec
+update refresh_tokens
+set
+  revoked_at = now(),
+  updated_at = now()
+where family_id = $1 and user_id = $2 and revoked_at is null;
+
+-- name: RevokeAllRefreshTokensForUser :exec
+update refresh_tokens
+set
+  revoked_at = now(),
+  updated_at = now()
+where user_id = $1 and revoked_at is null;
@@ -0,0 +1,31 @@
+-- name: SearchChirps :many
+-- Results are ranked by how well they match the text, then newest first;
+-- without text they all rank 0. Rechirps are left out so each chirp
+-- is found once.
+with matches as (
+  select chirps.*,
+    (case when @text::text = '' then 0
+      else ts_rank(to_tsvector('english', chirps.body), websearch_to_tsquery('english', @text::text))
+    end)::real as rank
+  from chirps
+  where chirps.kind <> 'rechirp'
+    and (@text::text = ''
+      or to_tsvector('english', chirps.body) @@ websearch_to_tsquery('english', @text::text))
+    and (sqlc.narg('from_handle')::text is null
+      or chirps.user_id = (select id from users where lower(handle) = sqlc.narg('from_handle')::text))
+    and (sqlc.narg('since')::timestamp is null or chirps.created_at >= sqlc.narg('since')::timestamp)
+    and (sqlc.narg('until')::timestamp is null or chirps.created_at < sqlc.narg('until')::timestamp)
+    and (cardinality(@hashtags::text[]) = 0 or chirps.id in (
+      select chirp_id from chirp_hashtags
+      where tag = any(@hashtags::text[])
+      group by chirp_id
+      having count(*) = cardinality(@hashtags::text[])))
+    and not exists (
+      select 1 from chirp_hashtags
+      where chirp_id = chirps.id and tag = any(@excluded_hashtags::text[]))
+)
+select id, created_at, updated_at, body, user_id, edited_at, in_reply_to, reply_count, like_count, kind, rechirp_of, quote_of, rank from matches
+where sqlc.narg('before_rank')::real is null
+  or (rank, created_at, id) < (sqlc.narg('before_rank')::real, sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+order by rank desc, created_at desc, id desc
+limit @page_size;
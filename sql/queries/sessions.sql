@@ -0,0 +1,37 @@
+-- name: CreateSession :one
+insert into sessions (
+  user_id, user_agent, ip_address
+) values (
+  $1, $2, $3
+) returning id;
+
+-- name: TouchSession :exec
+update sessions
+set
+  last_used_at = now(),
+  user_agent = $2,
+  ip_address = $3
+where id = $1;
+
+-- name: ListActiveSessions :many
+select * from sessions
+where user_id = $1
+  and revoked_at is null
+  and exists (
+    select 1 from refresh_tokens
+    where refresh_tokens.family_id = sessions.id
+      and refresh_tokens.revoked_at is null
+      and now() < refresh_tokens.expires_at
+  )
+order by last_used_at desc;
+
+-- name: RevokeSession :one
+update sessions
+set revoked_at = now()
+where id = $1 and user_id = $2 and revoked_at is null
+returning id;
+
+-- name: RevokeAllSessionsForUser :exec
+update sessions
+set revoked_at = now()
+where user_id = $1 and revoked_at is null;
@@ -0,0 +1,49 @@
+-- name: CreatePendingTOTPCredential :one
+insert into totp_credentials (
+  user_id, secret
+) values (
+  $1, $2
+)
+on conflict (user_id) do update
+set
+  secret = excluded.secret,
+  last_used_step = 0,
+  created_at = now()
+where totp_credentials.enabled_at is null
+returning user_id;
+
+-- name: GetTOTPCredential :one
+select * from totp_credentials where user_id = $1 limit 1;
+
+-- name: EnableTOTPCredential :exec
+update totp_credentials
+set enabled_at = now()
+where user_id = $1;
+
+-- name: UseTOTPStep :one
+update totp_credentials
+set last_used_step = $2
+where user_id = $1 and last_used_step < $2
+returning user_id;
+
+-- name: DeleteTOTPCredential :exec
+delete from totp_credentials where user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+insert into recovery_codes (
+  user_id, code_hash
+) values (
+  $1, $2
+);
+
+-- name: GetUnusedRecoveryCodes :many
+select * from recovery_codes where user_id = $1 and used_at is null;
+
+-- name: UseRecoveryCode :one
+update recovery_codes
+set used_at = now()
+where id = $1 and used_at is null
+returning id;
+
+-- name: DeleteRecoveryCodes :exec
+delete from recovery_codes where user_id = $1;
@@ -1,10 +1,11 @@
 -- name: CreateUser :one
 insert into users (
   email,
-  hashed_password
+  hashed_password,
+  handle
 ) values (
-  $1, $2
-) returning id, created_at, updated_at, email, is_chirpy_red;
+  $1, $2, $3
+) returning id, created_at, updated_at, email, is_chirpy_red, handle;
 
 -- name: DeleteAllUsers :exec
 delete from users;
@@ -16,20 +17,63 @@ select * from users where email = $1 limit 1;
 select * from users where id = $1 limit 1;
 
 
+-- name: PromoteFirstAdmin :one
+update users
+set role = 'admin'
+where email = $1
+  and not exists (select 1 from users where role = 'admin')
+returning id;
+
+-- name: SetUserRole :one
+update users
+set role = $2
+where id = $1
+returning id, created_at, updated_at, email, is_chirpy_red, role, handle;
+
 -- name: UpdateUserEmail :one
+-- Verifications, sign-in links and password resets sent to the old address
+-- are thrown away with it.
+with discarded as (
+  delete from email_verifications
+  where user_id = $1 and used_at is null
+), discarded_links as (
+  delete from magic_link_tokens
+  where user_id = $1 and used_at is null
+), discarded_resets as (
+  delete from password_reset_tokens
+  where user_id = $1 and used_at is null
+)
+update users
+set email = $2, email_verified_at = null
+where id = $1
+returning id, created_at, updated_at, email, is_chirpy_red, handle;
+
+-- name: UpdateUserHandle :one
 update users
-set email = $2
+set handle = $2
 where id = $1
-returning id, created_at, updated_at, email, is_chirpy_red;
+returning id, created_at, updated_at, email, is_chirpy_red, handle;
 
 -- name: UpdateUserPassword :one
 update users
 set hashed_password = $2
 where id = $1
-returning id, created_at, updated_at, email, is_chirpy_red;
+returning id, created_at, updated_at, email, is_chirpy_red, handle;
+
+-- name: UpgradeUserPasswordHash :execrows
+update users
+set hashed_password = @new_hash
+where id = @id and hashed_password = @old_hash;
 
 -- name: UpgradeUser :one
 update users
 set is_chirpy_red = true
 where id = $1
-returning id, created_at, updated_at, email, is_chirpy_red;
+returning id, created_at, updated_at, email, is_chirpy_red, handle;
+
+-- name: VerifyUserEmail :one
+-- Only verifies email if it is still the account's address.
+update users
+set email_verified_at = coalesce(email_verified_at, now())
+where id = $1 and email = $2
+returning id, created_at, updated_at, email, is_chirpy_red, handle, email_verified_at;
@@ -0,0 +1,41 @@
+-- name: CreateWebAuthnChallenge :one
+with expired as (
+  delete from webauthn_challenges where expires_at < now()
+)
+insert into webauthn_challenges (
+  user_id, challenge, ceremony, expires_at
+) values (
+  $1, $2, $3, $4
+)
+returning id;
+
+-- name: ConsumeWebAuthnChallenge :one
+delete from webauthn_challenges
+where id = $1 and ceremony = $2 and now() < expires_at
+returning user_id, challenge;
+
+-- name: CreateWebAuthnCredential :one
+insert into webauthn_credentials (
+  id, user_id, name, public_key, sign_count, transports
+) values (
+  $1, $2, $3, $4, $5, $6
+)
+returning *;
+
+-- name: GetWebAuthnCredential :one
+select * from webauthn_credentials
+where id = $1;
+
+-- name: ListWebAuthnCredentials :many
+select * from webauthn_credentials
+where user_id = $1
+order by created_at;
+
+-- name: UpdateWebAuthnSignCount :execrows
+update webauthn_credentials
+set sign_count = @new_sign_count, last_used_at = now()
+where id = @id and sign_count = @old_sign_count;
+
+-- name: DeleteWebAuthnCredential :execrows
+delete from webauthn_credentials
+where id = $1 and user_id = $2;
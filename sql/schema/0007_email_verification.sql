@@ -0,0 +1,21 @@
+-- +goose Up
+alter table users
+add column email_verified_at timestamp;
+
+-- accounts created before verification existed are trusted as-is
+update users set email_verified_at = now();
+
+create table email_verifications (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null,
+  expires_at timestamp not null,
+  used_at timestamp,
+  created_at timestamp default now(),
+  foreign key (user_id) references users(id) on delete cascade
+);
+
+-- +goose Down
+drop table email_verifications;
+
+alter table users
+drop column email_verified_at;
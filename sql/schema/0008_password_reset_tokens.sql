@@ -0,0 +1,12 @@
+-- +goose Up
+create table password_reset_tokens (
+  token_hash text primary key,
+  user_id uuid not null,
+  expires_at timestamp not null,
+  used_at timestamp,
+  created_at timestamp default now(),
+  foreign key (user_id) references users(id) on delete cascade
+);
+
+-- +goose Down
+drop table password_reset_tokens;
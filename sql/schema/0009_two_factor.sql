@@ -0,0 +1,24 @@
+-- +goose Up
+create table totp_credentials (
+  user_id uuid primary key,
+  secret text not null,
+  enabled_at timestamp,
+  last_used_step bigint not null default 0,
+  created_at timestamp default now(),
+  foreign key (user_id) references users(id) on delete cascade
+);
+
+create table recovery_codes (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null,
+  code_hash text not null,
+  used_at timestamp,
+  created_at timestamp default now(),
+  foreign key (user_id) references users(id) on delete cascade
+);
+
+create index recovery_codes_user_id_idx on recovery_codes (user_id);
+
+-- +goose Down
+drop table recovery_codes;
+drop table totp_credentials;
@@ -0,0 +1,36 @@
+-- +goose Up
+create table sessions (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null,
+  user_agent text,
+  ip_address text,
+  created_at timestamp not null default now(),
+  last_used_at timestamp not null default now(),
+  revoked_at timestamp,
+  foreign key (user_id) references users(id) on delete cascade
+);
+
+create index sessions_user_id_idx on sessions (user_id);
+
+-- every existing refresh token family becomes a session
+insert into sessions (id, user_id, created_at, last_used_at, revoked_at)
+select
+  family_id,
+  user_id,
+  coalesce(min(created_at), now()),
+  coalesce(max(updated_at), now()),
+  case when bool_and(revoked_at is not null) then max(revoked_at) end
+from refresh_tokens
+group by family_id, user_id;
+
+alter table refresh_tokens
+alter column family_id drop default,
+add constraint refresh_tokens_family_id_fkey
+  foreign key (family_id) references sessions(id) on delete cascade;
+
+-- +goose Down
+alter table refresh_tokens
+drop constraint refresh_tokens_family_id_fkey,
+alter column family_id set default gen_random_uuid();
+
+drop table sessions;
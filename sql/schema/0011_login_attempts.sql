@@ -0,0 +1,11 @@
+-- +goose Up
+-- keyed by "account:<email>" or "ip:<address>"
+create table login_attempts (
+  key text primary key,
+  failures integer not null default 0,
+  last_failure_at timestamp not null default now(),
+  locked_until timestamp
+);
+
+-- +goose Down
+drop table login_attempts;
@@ -0,0 +1,18 @@
+-- +goose Up
+create table api_tokens (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null,
+  name text not null,
+  token_hash text unique not null,
+  scopes text[] not null,
+  created_at timestamp not null default now(),
+  last_used_at timestamp,
+  expires_at timestamp,
+  revoked_at timestamp,
+  foreign key (user_id) references users(id) on delete cascade
+);
+
+create index api_tokens_user_id_idx on api_tokens (user_id);
+
+-- +goose Down
+drop table api_tokens;
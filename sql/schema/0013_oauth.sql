@@ -0,0 +1,33 @@
+-- +goose Up
+create table oauth_clients (
+  id text primary key,
+  owner_id uuid not null,
+  name text not null,
+  -- null for public clients (SPAs, mobile apps) that can't keep a secret
+  secret_hash text,
+  redirect_uris text[] not null,
+  created_at timestamp not null default now(),
+  foreign key (owner_id) references users(id) on delete cascade
+);
+
+create table oauth_authorization_codes (
+  code_hash text primary key,
+  client_id text not null,
+  user_id uuid not null,
+  redirect_uri text not null,
+  scopes text[] not null,
+  code_challenge text not null,
+  expires_at timestamp not null,
+  used_at timestamp,
+  created_at timestamp not null default now(),
+  foreign key (client_id) references oauth_clients(id) on delete cascade,
+  foreign key (user_id) references users(id) on delete cascade
+);
+
+alter table api_tokens
+  add column client_id text references oauth_clients(id) on delete cascade;
+
+-- +goose Down
+alter table api_tokens drop column client_id;
+drop table oauth_authorization_codes;
+drop table oauth_clients;
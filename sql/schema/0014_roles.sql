@@ -0,0 +1,7 @@
+-- +goose Up
+alter table users
+  add column role text not null default 'user'
+  constraint users_role_check check (role in ('user', 'moderator', 'admin'));
+
+-- +goose Down
+alter table users drop column role;
@@ -0,0 +1,24 @@
+-- +goose Up
+alter table users
+  add column deletion_requested_at timestamp,
+  add column delete_after timestamp;
+
+create index users_delete_after_idx on users (delete_after)
+  where delete_after is not null;
+
+-- what is left of a deleted account: enough to audit that it existed and
+-- was deleted, without keeping the email address itself
+create table deleted_users (
+  id uuid primary key,
+  email_hash text not null,
+  created_at timestamp,
+  deletion_requested_at timestamp,
+  deleted_at timestamp not null default now()
+);
+
+-- +goose Down
+drop table deleted_users;
+drop index users_delete_after_idx;
+alter table users
+  drop column delete_after,
+  drop column deletion_requested_at;
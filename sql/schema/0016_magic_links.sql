@@ -0,0 +1,14 @@
+-- +goose Up
+create table magic_link_tokens (
+  token_hash text primary key,
+  user_id uuid not null,
+  expires_at timestamp not null,
+  used_at timestamp,
+  created_at timestamp not null default now(),
+  foreign key (user_id) references users(id) on delete cascade
+);
+
+create index magic_link_tokens_user_id_idx on magic_link_tokens (user_id, created_at);
+
+-- +goose Down
+drop table magic_link_tokens;
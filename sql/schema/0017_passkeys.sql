@@ -0,0 +1,32 @@
+-- +goose Up
+create table webauthn_credentials (
+  id bytea primary key,
+  user_id uuid not null,
+  name text not null,
+  public_key bytea not null,
+  sign_count bigint not null default 0,
+  transports text[] not null default '{}',
+  created_at timestamp not null default now(),
+  last_used_at timestamp,
+  foreign key (user_id) references users(id) on delete cascade
+);
+
+create index webauthn_credentials_user_id_idx on webauthn_credentials (user_id);
+
+create table webauthn_challenges (
+  id uuid primary key default gen_random_uuid(),
+  -- null for logins, which don't know who the user is until they finish
+  user_id uuid,
+  challenge bytea not null,
+  ceremony text not null,
+  expires_at timestamp not null,
+  created_at timestamp not null default now(),
+  foreign key (user_id) references users(id) on delete cascade,
+  constraint webauthn_challenges_ceremony_check check (ceremony in ('registration', 'login'))
+);
+
+create index webauthn_challenges_expires_at_idx on webauthn_challenges (expires_at);
+
+-- +goose Down
+drop table webauthn_challenges;
+drop table webauthn_credentials;
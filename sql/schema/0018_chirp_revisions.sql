@@ -0,0 +1,19 @@
+-- +goose Up
+alter table chirps
+add column edited_at timestamp;
+
+create table chirp_revisions (
+  id uuid primary key default gen_random_uuid(),
+  chirp_id uuid not null,
+  body text not null,
+  -- when this body was written, and when an edit replaced it
+  written_at timestamp not null,
+  replaced_at timestamp not null default now(),
+  foreign key (chirp_id) references chirps(id) on delete cascade
+);
+
+create index chirp_revisions_chirp_id_idx on chirp_revisions (chirp_id, replaced_at);
+
+-- +goose Down
+drop table chirp_revisions;
+alter table chirps drop column edited_at;
@@ -0,0 +1,33 @@
+-- +goose Up
+-- replies outlive the chirp they answer; they just stop being replies
+alter table chirps
+  add column in_reply_to uuid references chirps(id) on delete set null,
+  add column reply_count integer not null default 0;
+
+create index chirps_in_reply_to_idx on chirps (in_reply_to, created_at)
+  where in_reply_to is not null;
+
+-- keeping the count on the chirp saves counting replies on every read
+-- +goose StatementBegin
+create function chirps_update_reply_count() returns trigger as $$
+begin
+  if tg_op = 'INSERT' and new.in_reply_to is not null then
+    update chirps set reply_count = reply_count + 1 where id = new.in_reply_to;
+  elsif tg_op = 'DELETE' and old.in_reply_to is not null then
+    update chirps set reply_count = reply_count - 1 where id = old.in_reply_to;
+  end if;
+  return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger chirps_reply_count
+after insert or delete on chirps
+for each row execute function chirps_update_reply_count();
+
+-- +goose Down
+drop trigger chirps_reply_count on chirps;
+drop function chirps_update_reply_count();
+alter table chirps
+  drop column reply_count,
+  drop column in_reply_to;
@@ -0,0 +1,21 @@
+-- +goose Up
+create table follows (
+  follower_id uuid not null,
+  followee_id uuid not null,
+  created_at timestamp not null default now(),
+  primary key (follower_id, followee_id),
+  foreign key (follower_id) references users(id) on delete cascade,
+  foreign key (followee_id) references users(id) on delete cascade,
+  constraint follows_not_self check (follower_id <> followee_id)
+);
+
+-- the primary key covers who a user follows; this covers their followers
+create index follows_followee_id_idx on follows (followee_id, created_at desc, follower_id desc);
+create index follows_follower_id_idx on follows (follower_id, created_at desc, followee_id desc);
+
+-- the timeline reads each followed user's newest chirps from here
+create index chirps_user_id_created_at_idx on chirps (user_id, created_at desc, id desc);
+
+-- +goose Down
+drop index chirps_user_id_created_at_idx;
+drop table follows;
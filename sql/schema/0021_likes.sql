@@ -0,0 +1,36 @@
+-- +goose Up
+alter table chirps
+add column like_count integer not null default 0;
+
+create table chirp_likes (
+  user_id uuid not null,
+  chirp_id uuid not null,
+  created_at timestamp not null default now(),
+  primary key (user_id, chirp_id),
+  foreign key (user_id) references users(id) on delete cascade,
+  foreign key (chirp_id) references chirps(id) on delete cascade
+);
+
+create index chirp_likes_user_id_created_at_idx on chirp_likes (user_id, created_at desc, chirp_id desc);
+
+-- +goose StatementBegin
+create function chirp_likes_update_like_count() returns trigger as $$
+begin
+  if tg_op = 'INSERT' then
+    update chirps set like_count = like_count + 1 where id = new.chirp_id;
+  else
+    update chirps set like_count = like_count - 1 where id = old.chirp_id;
+  end if;
+  return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger chirp_likes_like_count
+after insert or delete on chirp_likes
+for each row execute function chirp_likes_update_like_count();
+
+-- +goose Down
+drop table chirp_likes;
+drop function chirp_likes_update_like_count();
+alter table chirps drop column like_count;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- a rechirp is only a pointer, so it goes when the original does; a quote
+-- has its own words and stays, showing the original as deleted
+alter table chirps
+  add column kind text not null default 'chirp',
+  add column rechirp_of uuid references chirps(id) on delete cascade,
+  add column quote_of uuid references chirps(id) on delete set null,
+  add constraint chirps_kind_check check (kind in ('chirp', 'rechirp', 'quote')),
+  add constraint chirps_rechirp_check check (
+    (kind = 'rechirp') = (rechirp_of is not null)
+    and (kind <> 'rechirp' or body = '')
+  ),
+  add constraint chirps_quote_check check (kind = 'quote' or quote_of is null);
+
+-- each user can rechirp a chirp once
+create unique index chirps_rechirp_of_user_id_idx on chirps (rechirp_of, user_id)
+  where rechirp_of is not null;
+
+create index chirps_quote_of_idx on chirps (quote_of)
+  where quote_of is not null;
+
+-- +goose Down
+alter table chirps
+  drop column quote_of,
+  drop column rechirp_of,
+  drop column kind;
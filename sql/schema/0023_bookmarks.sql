@@ -0,0 +1,27 @@
+-- +goose Up
+create table bookmark_folders (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null references users(id) on delete cascade,
+  name text not null,
+  created_at timestamp not null default now(),
+  unique (user_id, name)
+);
+
+create table bookmarks (
+  user_id uuid not null,
+  chirp_id uuid not null,
+  -- null for bookmarks that aren't in a folder
+  folder_id uuid references bookmark_folders(id) on delete set null,
+  created_at timestamp not null default now(),
+  primary key (user_id, chirp_id),
+  foreign key (user_id) references users(id) on delete cascade,
+  foreign key (chirp_id) references chirps(id) on delete cascade
+);
+
+create index bookmarks_user_id_created_at_idx on bookmarks (user_id, created_at desc, chirp_id desc);
+create index bookmarks_folder_id_created_at_idx on bookmarks (folder_id, created_at desc, chirp_id desc)
+  where folder_id is not null;
+
+-- +goose Down
+drop table bookmarks;
+drop table bookmark_folders;
@@ -0,0 +1,25 @@
+-- +goose Up
+create table chirp_hashtags (
+  chirp_id uuid not null references chirps(id) on delete cascade,
+  -- normalized: lower case, without the #
+  tag text not null,
+  -- the chirp's created_at, so hashtag pages and trending read only this
+  -- table's indexes
+  created_at timestamp not null,
+  primary key (chirp_id, tag)
+);
+
+create index chirp_hashtags_tag_created_at_idx on chirp_hashtags (tag, created_at desc, chirp_id desc);
+create index chirp_hashtags_created_at_idx on chirp_hashtags (created_at);
+
+-- rewritten by the trending job every few minutes
+create table trending_hashtags (
+  tag text primary key,
+  score double precision not null,
+  chirp_count integer not null,
+  computed_at timestamp not null
+);
+
+-- +goose Down
+drop table trending_hashtags;
+drop table chirp_hashtags;
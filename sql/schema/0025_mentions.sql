@@ -0,0 +1,37 @@
+-- +goose Up
+alter table users
+  add column handle text,
+  add constraint users_handle_check check (handle ~ '^[A-Za-z0-9_]{1,15}$');
+
+-- handles keep the case users chose, but @Alice and @alice are the same
+create unique index users_handle_idx on users (lower(handle));
+
+-- mentions are resolved when a chirp is written, so a handle that changes
+-- hands later doesn't change who old chirps mention
+create table chirp_mentions (
+  chirp_id uuid not null references chirps(id) on delete cascade,
+  -- lower case, without the @
+  handle text not null,
+  user_id uuid not null references users(id) on delete cascade,
+  primary key (chirp_id, handle)
+);
+
+create index chirp_mentions_user_id_idx on chirp_mentions (user_id);
+
+create table notifications (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null references users(id) on delete cascade,
+  kind text not null check (kind in ('mention')),
+  actor_id uuid not null references users(id) on delete cascade,
+  chirp_id uuid not null references chirps(id) on delete cascade,
+  created_at timestamp not null default now(),
+  read_at timestamp
+);
+
+create index notifications_user_id_created_at_idx on notifications (user_id, created_at desc, id desc);
+
+-- +goose Down
+drop table notifications;
+drop table chirp_mentions;
+drop index users_handle_idx;
+alter table users drop column handle;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- search matches chirps with to_tsvector('english', body), so queries must
+-- use exactly that expression to use the index
+create index chirps_body_search_idx on chirps using gin (to_tsvector('english', body));
+
+-- +goose Down
+drop index chirps_body_search_idx;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- GET /api/chirps pages through every chirp by (created_at, id), in either
+-- direction; listing one author's chirps uses chirps_user_id_created_at_idx
+create index chirps_created_at_idx on chirps (created_at, id);
+
+-- +goose Down
+drop index chirps_created_at_idx;
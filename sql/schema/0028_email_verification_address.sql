@@ -0,0 +1,13 @@
+-- +goose Up
+-- the address a verification was sent to; using it verifies that address
+-- only, and only while the account still has it
+alter table email_verifications
+add column email text not null default '';
+
+-- verifications sent before this have no address and can't be used
+alter table email_verifications
+alter column email drop default;
+
+-- +goose Down
+alter table email_verifications
+drop column email;
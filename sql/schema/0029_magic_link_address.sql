@@ -0,0 +1,12 @@
+-- +goose Up
+-- the address a link was sent to; using the link verifies that address
+-- only if the account still has it
+alter table magic_link_tokens
+add column email text not null default '';
+
+alter table magic_link_tokens
+alter column email drop default;
+
+-- +goose Down
+alter table magic_link_tokens
+drop column email;
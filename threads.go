@@ -0,0 +1,114 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultThreadReplies = 50
+	maxThreadReplies     = 200
+)
+
+type ThreadReply struct {
+	Chirp
+	// 1 for direct replies to the chirp, 2 for replies to those, and so on
+	Depth int32 `json:"depth"`
+}
+
+// handlerGetThread returns the conversation around a chirp: every chirp it
+// replies to, root first, and a page of the replies below it. Replies come
+// depth first, each followed by its own replies, oldest first at every
+// level, so clients can rebuild the tree from in_reply_to and depth. Later
+// pages of replies are linked from the Link header.
+func (cfg *apiConfig) handlerGetThread(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Ancestors []Chirp       `json:"ancestors"`
+		Chirp     Chirp         `json:"chirp"`
+		Replies   []ThreadReply `json:"replies"`
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := pagination.ParseParams(req.URL.Query(), defaultThreadReplies, maxThreadReplies)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+	ancestors, err := cfg.db.GetChirpAncestors(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, "Could not get thread", http.StatusInternalServerError)
+		return
+	}
+	// replies sort by their path through the tree, so the last reply on a
+	// page is all the next page needs to know
+	_, afterID := cursorArgs(page.After)
+	replies, err := cfg.db.GetChirpDescendants(req.Context(), database.GetChirpDescendantsParams{
+		InReplyTo: uuid.NullUUID{UUID: chirpID, Valid: true},
+		AfterID:   afterID,
+		PageSize:  int32(page.Limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, "Could not get thread", http.StatusInternalServerError)
+		return
+	}
+	replies, more := pagination.Trim(replies, page.Limit)
+
+	// render the whole thread at once, in the order chirp, ancestors,
+	// replies, and split it up again afterwards
+	chirps := []database.Chirp{chirp}
+	for _, ancestor := range ancestors {
+		chirps = append(chirps, database.Chirp(ancestor))
+	}
+	for _, reply := range replies {
+		chirps = append(chirps, database.Chirp{
+			ID:         reply.ID,
+			CreatedAt:  reply.CreatedAt,
+			UpdatedAt:  reply.UpdatedAt,
+			Body:       reply.Body,
+			UserID:     reply.UserID,
+			EditedAt:   reply.EditedAt,
+			InReplyTo:  reply.InReplyTo,
+			ReplyCount: reply.ReplyCount,
+			LikeCount:  reply.LikeCount,
+			Kind:       reply.Kind,
+			RechirpOf:  reply.RechirpOf,
+			QuoteOf:    reply.QuoteOf,
+		})
+	}
+	rendered, err := cfg.renderChirps(req, chirps)
+	if err != nil {
+		respondWithError(w, "Could not get thread", http.StatusInternalServerError)
+		return
+	}
+
+	result := response{
+		Ancestors: rendered[1 : 1+len(ancestors)],
+		Chirp:     rendered[0],
+		Replies:   []ThreadReply{},
+	}
+	for i, reply := range replies {
+		result.Replies = append(result.Replies, ThreadReply{
+			Chirp: rendered[1+len(ancestors)+i],
+			Depth: reply.Depth,
+		})
+	}
+	if more {
+		last := replies[len(replies)-1]
+		pagination.SetNextLink(w, req, pagination.Cursor{CreatedAt: last.CreatedAt.Time, ID: last.ID})
+	}
+	respondWithJSON(w, result, http.StatusOK)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	mfaTokenExpiry    = 5 * time.Minute
+	recoveryCodeCount = 10
+	totpIssuer        = "Chirpy"
+)
+
+var errTOTPNotEnabled = errors.New("two-factor authentication is not enabled")
+
+type secondFactor struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (cfg *apiConfig) handlerEnrollTOTP(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	type response struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+
+	user, err := cfg.db.GetUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	// replaces any enrollment that was never confirmed, but never an active one
+	_, err = cfg.db.CreatePendingTOTPCredential(req.Context(), database.CreatePendingTOTPCredentialParams{
+		UserID: user.ID,
+		Secret: secret,
+	})
+	if err != nil {
+		respondWithError(w, "two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	respondWithJSON(w, response{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(secret, user.Email, totpIssuer),
+	}, http.StatusCreated)
+}
+
+func (cfg *apiConfig) handlerConfirmTOTP(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed confirmation request", http.StatusBadRequest)
+		return
+	}
+
+	credential, err := cfg.db.GetTOTPCredential(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, "two-factor authentication has not been enrolled", http.StatusNotFound)
+		return
+	}
+	if credential.EnabledAt.Valid {
+		respondWithError(w, "two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	if !cfg.checkTOTPCode(req.Context(), credential, params.Code) {
+		respondWithError(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if err := cfg.db.DeleteRecoveryCodes(req.Context(), userID); err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	for _, code := range codes {
+		hashed, err := auth.HashPasswordWithParams(code, cfg.passwordParams)
+		if err != nil {
+			respondWithError(w, "failed to hash recovery code", http.StatusInternalServerError)
+			return
+		}
+		err = cfg.db.CreateRecoveryCode(req.Context(), database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashed,
+		})
+		if err != nil {
+			respondWithError(w, "failed to store recovery code", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := cfg.db.EnableTOTPCredential(req.Context(), userID); err != nil {
+		respondWithError(w, "failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, response{RecoveryCodes: codes}, http.StatusOK)
+}
+
+func (cfg *apiConfig) handlerDisableTOTP(w http.ResponseWriter, req *http.Request) {
+	token := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWTWithSigner(token, cfg.signer)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	params := secondFactor{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed request", http.StatusBadRequest)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(req.Context(), userID, params)
+	if err != nil {
+		respondWithError(w, "two-factor authentication is not enabled", http.StatusNotFound)
+		return
+	}
+	if !ok {
+		respondWithError(w, "invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	if err := cfg.db.DeleteTOTPCredential(req.Context(), userID); err != nil {
+		respondWithError(w, "failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if err := cfg.db.DeleteRecoveryCodes(req.Context(), userID); err != nil {
+		respondWithError(w, "failed to delete recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerLoginMFA(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		MFAToken string `json:"mfa_token"`
+		secondFactor
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed login form", http.StatusBadRequest)
+		return
+	}
+
+	userID, _, err := auth.ValidateActionToken(cfg.signer, params.MFAToken, auth.ActionMFA)
+	if err != nil {
+		respondWithError(w, "invalid or expired mfa token", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := cfg.db.GetUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if !cfg.checkLoginLockout(w, req, user.Email) {
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(req.Context(), user.ID, params.secondFactor)
+	if err != nil || !ok {
+		cfg.recordLoginFailure(req.Context(), req, user.Email)
+		respondWithError(w, "invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	cfg.issueSession(w, req, user, cfg.jwtExpiry)
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code for a user with two-factor authentication enabled. It only errors when
+// the user has no enabled credential.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, userID uuid.UUID, factor secondFactor) (bool, error) {
+	credential, err := cfg.db.GetTOTPCredential(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !credential.EnabledAt.Valid {
+		return false, errTOTPNotEnabled
+	}
+	if factor.RecoveryCode != "" {
+		return cfg.useRecoveryCode(ctx, userID, factor.RecoveryCode), nil
+	}
+	return cfg.checkTOTPCode(ctx, credential, factor.Code), nil
+}
+
+// checkTOTPCode validates code and marks its time step as used, so the same
+// code can't be replayed while it is still current.
+func (cfg *apiConfig) checkTOTPCode(ctx context.Context, credential database.TotpCredential, code string) bool {
+	step, ok, err := auth.ValidateTOTPCode(credential.Secret, code, time.Now())
+	if err != nil || !ok {
+		return false
+	}
+	_, err = cfg.db.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		UserID:       credential.UserID,
+		LastUsedStep: step,
+	})
+	return err == nil
+}
+
+func (cfg *apiConfig) useRecoveryCode(ctx context.Context, userID uuid.UUID, code string) bool {
+	codes, err := cfg.db.GetUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return false
+	}
+	for _, stored := range codes {
+		match, err := auth.CheckPasswordHash(code, stored.CodeHash)
+		if err != nil || !match {
+			continue
+		}
+		_, err = cfg.db.UseRecoveryCode(ctx, stored.ID)
+		return err == nil
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/mailer"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const emailVerificationExpiry = 24 * time.Hour
+
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	expiresAt := time.Now().Add(emailVerificationExpiry)
+	tokenID, err := cfg.db.CreateEmailVerification(ctx, database.CreateEmailVerificationParams{
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store email verification: %s", err)
+	}
+	token, err := auth.MakeActionToken(cfg.signer, userID, tokenID, auth.ActionVerifyEmail, emailVerificationExpiry)
+	if err != nil {
+		return fmt.Errorf("failed to sign email verification token: %s", err)
+	}
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: "Welcome to Chirpy!\n\n" +
+			"To verify your email address, send the token below to POST /api/users/verify.\n" +
+			"It expires in 24 hours and can only be used once.\n\n" +
+			token + "\n",
+	})
+}
+
+func (cfg *apiConfig) handlerVerifyEmail(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	params := parameters{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, "malformed verification request", http.StatusBadRequest)
+		return
+	}
+
+	userID, tokenID, err := auth.ValidateActionToken(cfg.signer, params.Token, auth.ActionVerifyEmail)
+	if err != nil {
+		respondWithError(w, "invalid or expired verification token", http.StatusBadRequest)
+		return
+	}
+
+	verification, err := cfg.db.ConsumeEmailVerification(req.Context(), tokenID)
+	if err != nil || verification.UserID != userID {
+		respondWithError(w, "invalid or expired verification token", http.StatusBadRequest)
+		return
+	}
+
+	// the token only vouches for the address it was sent to, which the
+	// account may no longer have
+	user, err := cfg.db.VerifyUserEmail(req.Context(), database.VerifyUserEmailParams{
+		ID:    userID,
+		Email: verification.Email,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "invalid or expired verification token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, User{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		IsChirpyRed: user.IsChirpyRed.Bool,
+		Handle:      stringOrNil(user.Handle),
+	}, http.StatusOK)
+}